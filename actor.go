@@ -0,0 +1,168 @@
+package saferr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gford1000-go/saferr/types"
+)
+
+// ErrActorHook is returned by GoActor if the state type of a WithRestore or WithSnapshot hook does
+// not match that of the actor
+var ErrActorHook = &Error{Code: CodeInvalidArgument, Message: "actor hook has the wrong state type"}
+
+// ErrSnapshotFailed is passed to the WithSnapshotErrorHandler hook if a periodic WithSnapshot hook fails,
+// and joined to the error passed to the GoPostEnd hook if the snapshot taken when the actor ends fails
+var ErrSnapshotFailed = &Error{Code: CodeInternal, Message: "actor snapshot failed"}
+
+// ActorHandler handles a request for an actor created by GoActor, with exclusive access to its state
+type ActorHandler[S, T, U any] func(ctx context.Context, s *S, t *T) (*U, error)
+
+// actor owns the state and current behaviour of a GoActor.  It is only accessed from the
+// Responder goroutine, and so needs no locking
+type actor[S, T, U any] struct {
+	ctx       context.Context
+	state     S
+	behaviour ActorHandler[S, T, U]
+	restore   func(context.Context) (*S, error)
+	snapshot  func(context.Context, *S) error
+	every     int
+	onError   func(err error)
+	handled   int
+	started   bool
+}
+
+type actorKey struct{}
+
+// GoActor behaves as Go, with the Responder goroutine exclusively owning the state S, which starts as
+// initial and is passed to handler with each request.  Since the Responder handles one request at a time,
+// in FIFO sequence, the state never needs locking; WithWorkers is therefore ignored.
+//
+// The behaviour for subsequent requests can be changed by handler calling Become.  The state can be
+// restored when the actor starts using WithRestore, and saved periodically and when the actor ends using WithSnapshot.
+// An error is returned if the state type of either hook is not S
+func GoActor[S, T, U any](ctx context.Context, initial S, handler ActorHandler[S, T, U], opts ...func(*Options)) (types.Requestor[T, U], error) {
+	o := defaults
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	a := &actor[S, T, U]{state: initial, behaviour: handler, every: o.actorSnapshotEvery, onError: o.OnSnapshotError}
+	if err := a.hooks(o.actorRestore, o.actorSnapshot); err != nil {
+		return nil, err
+	}
+
+	preStart, postEnd := o.GoPreStart, o.GoPostEnd
+
+	opts = append(opts[:len(opts):len(opts)],
+		WithWorkers(1),
+		WithGoPreStart(func(ctx context.Context) (context.Context, error) {
+			var err error
+			if preStart != nil {
+				if ctx, err = preStart(ctx); err != nil {
+					return ctx, err
+				}
+			}
+			if a.restore != nil {
+				s, err := a.restore(ctx)
+				if err != nil {
+					return ctx, err
+				}
+				if s != nil {
+					a.state = *s
+				}
+			}
+			a.ctx = context.WithValue(ctx, actorKey{}, a)
+			a.started = true
+			return a.ctx, nil
+		}),
+		WithGoPostEnd(func(err error) {
+			if a.started && a.snapshot != nil {
+				if serr := a.snapshot(context.WithoutCancel(a.ctx), &a.state); serr != nil {
+					err = errors.Join(err, fmt.Errorf("%w: %w", ErrSnapshotFailed, serr))
+				}
+			}
+			if postEnd != nil {
+				postEnd(err)
+			}
+		}))
+
+	return Go(ctx, a.handle, opts...), nil
+}
+
+// hooks sets the restore and snapshot hooks, checking they have the state type of the actor
+func (a *actor[S, T, U]) hooks(restore, snapshot any) error {
+	if restore != nil {
+		f, ok := restore.(func(context.Context) (*S, error))
+		if !ok {
+			return fmt.Errorf("%w: restore is %T", ErrActorHook, restore)
+		}
+		a.restore = f
+	}
+	if snapshot != nil {
+		f, ok := snapshot.(func(context.Context, *S) error)
+		if !ok {
+			return fmt.Errorf("%w: snapshot is %T", ErrActorHook, snapshot)
+		}
+		a.snapshot = f
+	}
+	return nil
+}
+
+func (a *actor[S, T, U]) handle(ctx context.Context, t *T) (*U, error) {
+	u, err := a.behaviour(ctx, &a.state, t)
+
+	// A failed snapshot does not affect the request, which has been handled
+	a.handled++
+	if a.snapshot != nil && a.every > 0 && a.handled%a.every == 0 {
+		if serr := a.snapshot(ctx, &a.state); serr != nil && a.onError != nil {
+			a.onError(fmt.Errorf("%w: %w", ErrSnapshotFailed, serr))
+		}
+	}
+
+	return u, err
+}
+
+// Become changes the ActorHandler used by the actor handling the request in ctx, starting with the next
+// request.  Returns false if ctx was not passed to an ActorHandler of a GoActor with the same types
+func Become[S, T, U any](ctx context.Context, handler ActorHandler[S, T, U]) bool {
+	a, ok := ctx.Value(actorKey{}).(*actor[S, T, U])
+	if !ok || handler == nil {
+		return false
+	}
+	a.behaviour = handler
+	return true
+}
+
+// WithRestore sets the hook called by GoActor before handling the first request, which returns the state
+// to use instead of the initial state, for example from a snapshot.  A nil state leaves the initial state unchanged.
+// If an error is returned, the actor ends without handling any requests
+func WithRestore[S any](f func(ctx context.Context) (*S, error)) func(*Options) {
+	return func(o *Options) {
+		if f != nil {
+			o.actorRestore = f
+		}
+	}
+}
+
+// WithSnapshot sets the hook called by GoActor with its state after every n requests (if n is greater
+// than zero), and when the actor ends.  The state must not be retained or modified by f, since it
+// continues to be owned by the actor.  Errors are passed to the WithSnapshotErrorHandler hook, or to
+// the GoPostEnd hook for the snapshot taken when the actor ends
+func WithSnapshot[S any](n int, f func(ctx context.Context, s *S) error) func(*Options) {
+	return func(o *Options) {
+		if f != nil {
+			o.actorSnapshot = f
+			o.actorSnapshotEvery = n
+		}
+	}
+}
+
+// WithSnapshotErrorHandler sets the function called by GoActor if a periodic WithSnapshot hook fails, since
+// the failure does not affect the request after which the snapshot was taken
+func WithSnapshotErrorHandler(f func(err error)) func(*Options) {
+	return func(o *Options) {
+		o.OnSnapshotError = f
+	}
+}
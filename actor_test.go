@@ -0,0 +1,162 @@
+package saferr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func ExampleGoActor() {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	type account struct {
+		balance int
+	}
+
+	var open, frozen ActorHandler[account, int, int]
+
+	open = func(ctx context.Context, s *account, amount *int) (*int, error) {
+		if s.balance+*amount < 0 {
+			Become(ctx, frozen)
+			return nil, errors.New("overdrawn: account frozen")
+		}
+		s.balance += *amount
+		return &s.balance, nil
+	}
+
+	frozen = func(ctx context.Context, s *account, amount *int) (*int, error) {
+		return nil, errors.New("account frozen")
+	}
+
+	requestor, _ := GoActor(ctx, account{balance: 100}, open)
+
+	for _, amount := range []int{50, -200, 10} {
+		balance, err := requestor.Send(ctx, &amount)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		fmt.Println(*balance)
+	}
+
+	// Output:
+	// 150
+	// overdrawn: account frozen
+	// account frozen
+}
+
+func TestGoActor_SnapshotRestore(t *testing.T) {
+
+	type counter struct {
+		N int
+	}
+
+	increment := func(ctx context.Context, s *counter, t *int) (*int, error) {
+		s.N += *t
+		return &s.N, nil
+	}
+
+	var lck sync.Mutex
+	var snapshots []int
+	ended := make(chan error, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	requestor, err := GoActor(ctx, counter{}, increment,
+		WithRestore(func(ctx context.Context) (*counter, error) {
+			return &counter{N: 10}, nil
+		}),
+		WithSnapshot(2, func(ctx context.Context, s *counter) error {
+			lck.Lock()
+			defer lck.Unlock()
+			snapshots = append(snapshots, s.N)
+			return nil
+		}),
+		WithGoPostEnd(func(err error) { ended <- err }))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 1; i <= 3; i++ {
+		n, err := requestor.Send(ctx, &i)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if want := 10 + i*(i+1)/2; *n != want {
+			t.Fatalf("expected %d, got %d", want, *n)
+		}
+	}
+
+	cancel()
+
+	select {
+	case err := <-ended:
+		if !errors.Is(err, ErrContextCompleted) {
+			t.Fatalf("expected ErrContextCompleted, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("actor did not end")
+	}
+
+	lck.Lock()
+	defer lck.Unlock()
+
+	// After the second request, and then when the actor ends
+	if fmt.Sprint(snapshots) != "[13 16]" {
+		t.Fatalf("unexpected snapshots: %v", snapshots)
+	}
+}
+
+func TestGoActor_SnapshotFailed(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errDisk := errors.New("disk full")
+	failed := make(chan error, 1)
+
+	requestor, _ := GoActor(ctx, 0, func(ctx context.Context, s *int, t *int) (*int, error) {
+		*s += *t
+		return s, nil
+	},
+		WithSnapshot(1, func(ctx context.Context, s *int) error { return errDisk }),
+		WithSnapshotErrorHandler(func(err error) { failed <- err }))
+
+	// The request succeeds, and the failure is reported separately
+	v := 1
+	n, err := requestor.Send(ctx, &v)
+	if err != nil || n == nil || *n != 1 {
+		t.Fatalf("expected the response to be returned, got %v, %v", n, err)
+	}
+	if err := <-failed; !errors.Is(err, ErrSnapshotFailed) || !errors.Is(err, errDisk) {
+		t.Fatalf("expected ErrSnapshotFailed, got %v", err)
+	}
+}
+
+func TestGoActor_HookTypeMismatch(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	handler := func(ctx context.Context, s *int, t *int) (*int, error) {
+		return s, nil
+	}
+
+	if _, err := GoActor(ctx, 0, handler, WithRestore(func(ctx context.Context) (*string, error) { return nil, nil })); !errors.Is(err, ErrActorHook) {
+		t.Fatalf("expected ErrActorHook, got %v", err)
+	}
+	if _, err := GoActor(ctx, 0, handler, WithSnapshot(1, func(ctx context.Context, s *string) error { return nil })); !errors.Is(err, ErrActorHook) {
+		t.Fatalf("expected ErrActorHook, got %v", err)
+	}
+}
+
+func TestBecome_NotActor(t *testing.T) {
+	if Become(context.Background(), func(ctx context.Context, s *int, t *int) (*int, error) { return nil, nil }) {
+		t.Fatal("expected Become to fail outside an actor")
+	}
+}
@@ -0,0 +1,145 @@
+package saferr
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gford1000-go/saferr/types"
+)
+
+// ErrBatchResultMismatch returned to each request of a batch if the BatchHandler does not return
+// a response (and, if any, an error) for every request
+var ErrBatchResultMismatch = &Error{Code: CodeInternal, Message: "batch handler result count mismatch"}
+
+// GoBatch behaves as Go, except that the Responder takes up to MaxBatchSize requests at a time, waiting up
+// to BatchLinger for the batch to fill, and passes them together to handler.  Each response and error is
+// returned to the Requestor that sent the corresponding request.  Batches are handled one at a time, in the
+// sequence their requests were taken, so the Workers option is ignored
+func GoBatch[T any, U any](ctx context.Context, handler types.BatchHandler[T, U], opts ...func(*Options)) types.Requestor[T, U] {
+	requestor, receiver := New[T, U](ctx, opts...)
+
+	o := defaults
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	r := receiver.(*responder[T, U])
+	go serve(ctx, receiver, &o, func(ctx context.Context) error {
+		return r.listenAndHandleBatch(ctx, handler, o.MaxBatchSize, o.BatchLinger)
+	})
+
+	return requestor
+}
+
+// listenAndHandleBatch behaves as ListenAndHandle, but handles a batch of requests
+func (r *responder[T, U]) listenAndHandleBatch(ctx context.Context, h types.BatchHandler[T, U], max int, linger time.Duration) error {
+	first, err := r.listen(ctx)
+	if first == nil {
+		return err
+	}
+
+	batch := r.collect(ctx, []*req[T, U]{first}, max, linger)
+
+	if r.isClosed() {
+		for _, req := range batch {
+			req.c.send(r.pool.Get(req.id, nil, ErrResponderIsClosed))
+		}
+		return nil
+	}
+	r.hasGoneAway = r.clock.Now().Add(r.requestorGoneAwayTimeout) // Reset the gone away timer
+
+	r.handleBatch(ctx, h, batch)
+	return nil
+}
+
+// poll takes the next request without blocking, returning nil if there is none waiting
+func (r *responder[T, U]) poll() *req[T, U] {
+	if r.sched != nil {
+		return r.sched.next()
+	}
+	select {
+	case req := <-r.ch:
+		return req
+	default:
+		return nil
+	}
+}
+
+// collect adds the requests that are waiting to the batch, then waits up to linger for more, until there are max
+func (r *responder[T, U]) collect(ctx context.Context, batch []*req[T, U], max int, linger time.Duration) []*req[T, U] {
+	drain := func() {
+		for len(batch) < max {
+			req := r.poll()
+			if req == nil {
+				return
+			}
+			batch = append(batch, req)
+		}
+	}
+
+	drain()
+	if len(batch) >= max || linger <= 0 {
+		return batch
+	}
+
+	var ready <-chan struct{}
+	if r.sched != nil {
+		ready = r.sched.ready()
+	}
+
+	lingerTimer := acquireTimer(r.clock, linger)
+	defer releaseTimer(lingerTimer)
+
+	for len(batch) < max {
+		select {
+		case <-lingerTimer.C():
+			return batch
+		case <-r.ctx.Done():
+			return batch
+		case <-ctx.Done():
+			return batch
+		case req := <-r.ch:
+			batch = append(batch, req)
+		case <-ready:
+			drain()
+		}
+	}
+	return batch
+}
+
+func (r *responder[T, U]) handleBatch(ctx context.Context, h types.BatchHandler[T, U], batch []*req[T, U]) {
+	// As with handle, copy the details of each request before calling the handler
+	cs := make([]*correlatedChan[U], len(batch))
+	ids := make([]uint64, len(batch))
+	ts := make([]*T, len(batch))
+	for i, req := range batch {
+		cs[i], ids[i], ts[i] = req.c, req.id, req.data
+	}
+
+	sendAll := func(err error) {
+		for i := range cs {
+			r.sendResp(cs[i], r.pool.Get(ids[i], nil, err))
+		}
+	}
+
+	defer func() {
+		if rc := recover(); rc != nil {
+			sendAll(fmt.Errorf("%w: %v", ErrUncaughtHandlerPanic, rc))
+		}
+	}()
+
+	us, errs := h(ctx, ts)
+	if len(us) != len(ts) || (errs != nil && len(errs) != len(ts)) {
+		sendAll(fmt.Errorf("%w: %d requests, %d responses, %d errors", ErrBatchResultMismatch, len(ts), len(us), len(errs)))
+		return
+	}
+
+	for i := range cs {
+		var err error
+		if errs != nil {
+			err = errs[i]
+		}
+		r.sendResp(cs[i], r.pool.Get(ids[i], us[i], err))
+	}
+}
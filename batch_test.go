@@ -0,0 +1,160 @@
+package saferr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func ExampleGoBatch() {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// A writer that inserts each batch of rows with a single statement
+	insert := func(ctx context.Context, rows []*string) ([]*int, []error) {
+		ids := make([]*int, len(rows))
+		for i := range rows {
+			id := 100 + i
+			ids[i] = &id
+		}
+		return ids, nil
+	}
+
+	requestor := GoBatch(ctx, insert, WithMaxBatchSize(50), WithBatchLinger(time.Millisecond))
+
+	row := "row"
+	id, _ := requestor.Send(ctx, &row)
+	fmt.Println(*id)
+
+	// Output:
+	// 100
+}
+
+func TestGoBatch_Batches(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var lck sync.Mutex
+	var sizes []int
+	double := func(ctx context.Context, ns []*int) ([]*int, []error) {
+		lck.Lock()
+		sizes = append(sizes, len(ns))
+		lck.Unlock()
+
+		us := make([]*int, len(ns))
+		errs := make([]error, len(ns))
+		for i, n := range ns {
+			if *n%7 == 0 {
+				errs[i] = fmt.Errorf("unlucky %d", *n)
+				continue
+			}
+			m := *n * 2
+			us[i] = &m
+		}
+		return us, errs
+	}
+
+	requestor := GoBatch(ctx, double, WithMaxBatchSize(4), WithBatchLinger(100*time.Millisecond))
+
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			u, err := requestor.Send(ctx, &i)
+			if i%7 == 0 {
+				if err == nil || err.Error() != fmt.Sprintf("unlucky %d", i) {
+					t.Errorf("expected error for %d, got %v", i, err)
+				}
+				return
+			}
+			if err != nil || *u != i*2 {
+				t.Errorf("expected %d, got %v, %v", i*2, u, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	total := 0
+	for _, size := range sizes {
+		if size > 4 {
+			t.Fatalf("batch of %d exceeds maximum", size)
+		}
+		total += size
+	}
+	if total != 10 || len(sizes) > 4 {
+		t.Fatalf("expected 10 requests in at most 4 batches, got %v", sizes)
+	}
+}
+
+func TestGoBatch_Mismatch(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	short := func(ctx context.Context, ns []*int) ([]*int, []error) {
+		return nil, nil
+	}
+
+	requestor := GoBatch(ctx, short)
+
+	n := 1
+	if _, err := requestor.Send(ctx, &n); !errors.Is(err, ErrBatchResultMismatch) {
+		t.Fatalf("expected ErrBatchResultMismatch, got %v", err)
+	}
+}
+
+func TestGoBatch_Panic(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	boom := func(ctx context.Context, ns []*int) ([]*int, []error) {
+		panic("boom")
+	}
+
+	requestor := GoBatch(ctx, boom, WithBatchLinger(20*time.Millisecond))
+
+	var wg sync.WaitGroup
+	for i := range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := requestor.Send(ctx, &i); !errors.Is(err, ErrUncaughtHandlerPanic) {
+				t.Errorf("expected ErrUncaughtHandlerPanic, got %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestGoBatch_FairQueueing(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	echo := func(ctx context.Context, ss []*string) ([]*string, []error) {
+		return ss, nil
+	}
+
+	requestor := GoBatch(ctx, echo, WithFairQueueing[string](nil), WithBatchLinger(10*time.Millisecond))
+
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s := fmt.Sprint(i)
+			u, err := requestor.Send(WithTenant(ctx, fmt.Sprint(i%3)), &s)
+			if err != nil || *u != s {
+				t.Errorf("expected %s, got %v, %v", s, u, err)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,329 @@
+package chaos
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gford1000-go/saferr"
+	"github.com/gford1000-go/saferr/types"
+)
+
+// ErrInjected is returned by an Error fault, unless the Fault specifies its own error
+var ErrInjected = &saferr.Error{Code: saferr.CodeUnavailable, Message: "chaos: injected error", Retryable: true}
+
+// ErrInjectedExit is returned for the request that triggered an Exit fault
+var ErrInjectedExit = &saferr.Error{Code: saferr.CodeUnavailable, Message: "chaos: injected responder exit"}
+
+// Kind identifies the type of failure injected by a Fault
+type Kind int
+
+const (
+	// Latency delays the request by the Fault Duration, before continuing
+	Latency Kind = iota
+	// Error returns the Fault Err (or ErrInjected) without calling the handler
+	Error
+	// Panic causes the handler to panic, which the Responder converts to saferr.ErrUncaughtHandlerPanic
+	Panic
+	// Drop holds the response for the Fault Duration, which should exceed the RequestorTimeout,
+	// so that the Requestor gives up and the response is discarded.  In a Handler, the Responder
+	// goroutine (or worker) is held for the Duration too, delaying the requests queued behind it
+	Drop
+	// Exit causes the Responder goroutine started by saferr.Go to exit. This requires the
+	// Injector's GoOption to be passed to saferr.Go
+	Exit
+)
+
+var kindNames = map[Kind]string{
+	Latency: "latency",
+	Error:   "error",
+	Panic:   "panic",
+	Drop:    "drop",
+	Exit:    "exit",
+}
+
+// String returns the name of the Kind
+func (k Kind) String() string {
+	if s, ok := kindNames[k]; ok {
+		return s
+	}
+	return fmt.Sprintf("kind(%d)", int(k))
+}
+
+// Fault describes a failure to be injected, and which requests it applies to
+type Fault struct {
+	// Kind is the type of failure
+	Kind Kind
+	// Probability in the range [0, 1] that the Fault is applied to a request.  Ignored if Schedule is set
+	Probability float64
+	// Schedule deterministically decides whether the Fault is applied, given the request key
+	// and the count of requests seen so far for that key (starting at 1)
+	Schedule func(key string, n uint64) bool
+	// Keys restricts the Fault to requests with these keys.  All keys are eligible if empty
+	Keys []string
+	// Duration of a Latency or Drop fault
+	Duration time.Duration
+	// Err returned by an Error fault.  ErrInjected is used if nil
+	Err error
+}
+
+func (f *Fault) applies(key string, n uint64, rnd func() float64) bool {
+	if len(f.Keys) > 0 {
+		found := false
+		for _, k := range f.Keys {
+			if k == key {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.Schedule != nil {
+		return f.Schedule(key, n)
+	}
+	return f.Probability > 0 && rnd() < f.Probability
+}
+
+// Always is a Schedule that applies the Fault to every request
+func Always() func(key string, n uint64) bool {
+	return func(string, uint64) bool { return true }
+}
+
+// Every is a Schedule that applies the Fault to every nth request for each key
+func Every(n uint64) func(key string, count uint64) bool {
+	return func(_ string, count uint64) bool {
+		return n > 0 && count%n == 0
+	}
+}
+
+// Nth is a Schedule that applies the Fault only to the specified request counts for each key
+func Nth(ns ...uint64) func(key string, count uint64) bool {
+	return func(_ string, count uint64) bool {
+		for _, n := range ns {
+			if n == count {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Config declares the Faults that an Injector will apply
+type Config[T any] struct {
+	// Faults are evaluated in order for each request.  Latency faults are cumulative;
+	// the first other Fault that applies ends the evaluation
+	Faults []*Fault
+	// KeyFunc returns the key of the request, used by Fault Keys and Schedules.
+	// All requests share the empty key if nil.  See MuxKey for mux Requests
+	KeyFunc func(*T) string
+	// Seed for the random number generator used with Fault Probability, so runs can be reproduced
+	Seed uint64
+	// Clock used for Latency and Drop faults.  The wall clock is used if nil
+	Clock saferr.Clock
+}
+
+// MuxKey returns a KeyFunc that uses the Key of a mux Request
+func MuxKey[T, M any, K comparable]() func(*types.Request[T, M, K]) string {
+	return func(r *types.Request[T, M, K]) string {
+		return fmt.Sprint(r.Key)
+	}
+}
+
+// Injector applies the configured Faults to requests, via Handler or Requestor.
+// An Injector is enabled on creation, and is safe for concurrent use.
+type Injector[T any] struct {
+	cfg     Config[T]
+	enabled atomic.Bool
+	lck     sync.Mutex
+	rnd     *rand.Rand
+	counts  map[string]uint64
+	stats   map[Kind]uint64
+}
+
+// New creates an Injector for the specified Config
+func New[T any](cfg Config[T]) *Injector[T] {
+	if cfg.Clock == nil {
+		cfg.Clock = saferr.RealClock()
+	}
+	i := &Injector[T]{
+		cfg:    cfg,
+		rnd:    rand.New(rand.NewPCG(cfg.Seed, cfg.Seed)),
+		counts: map[string]uint64{},
+		stats:  map[Kind]uint64{},
+	}
+	i.enabled.Store(true)
+	return i
+}
+
+// SetEnabled turns fault injection on or off, allowing the Injector to remain in place but inactive
+func (i *Injector[T]) SetEnabled(enabled bool) {
+	i.enabled.Store(enabled)
+}
+
+// Enabled returns whether fault injection is on
+func (i *Injector[T]) Enabled() bool {
+	return i.enabled.Load()
+}
+
+// Stats returns the number of times each Kind of Fault has been injected
+func (i *Injector[T]) Stats() map[Kind]uint64 {
+	i.lck.Lock()
+	defer i.lck.Unlock()
+	result := make(map[Kind]uint64, len(i.stats))
+	for k, v := range i.stats {
+		result[k] = v
+	}
+	return result
+}
+
+// faultsFor returns the Faults to apply to the request, namely any Latency faults plus
+// at most one other Fault.  Panic and Exit faults only apply to Handlers, and so are
+// neither applied nor counted for a Requestor
+func (i *Injector[T]) faultsFor(t *T, handler bool) []*Fault {
+	if !i.Enabled() {
+		return nil
+	}
+
+	var key string
+	if i.cfg.KeyFunc != nil && t != nil {
+		key = i.cfg.KeyFunc(t)
+	}
+
+	i.lck.Lock()
+	defer i.lck.Unlock()
+
+	i.counts[key]++
+	n := i.counts[key]
+
+	var result []*Fault
+	for _, f := range i.cfg.Faults {
+		if !handler && (f.Kind == Panic || f.Kind == Exit) {
+			continue
+		}
+		if !f.applies(key, n, i.rnd.Float64) {
+			continue
+		}
+		i.stats[f.Kind]++
+		result = append(result, f)
+		if f.Kind != Latency {
+			break
+		}
+	}
+	return result
+}
+
+func (i *Injector[T]) wait(ctx context.Context, d time.Duration) error {
+	timer := i.cfg.Clock.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C():
+		return nil
+	case <-ctx.Done():
+		return saferr.ErrContextCompleted
+	}
+}
+
+type exitKey struct{}
+
+// GoOption returns an option for saferr.Go that allows Exit faults to stop the Responder goroutine.
+// Any GoPreStart and GoPostEnd hooks already set are retained, so this should be specified after
+// saferr.WithGoPreStart and saferr.WithGoPostEnd
+func (i *Injector[T]) GoOption() func(*saferr.Options) {
+	return func(o *saferr.Options) {
+		var cancel context.CancelFunc
+
+		prevStart := o.GoPreStart
+		o.GoPreStart = func(ctx context.Context) (context.Context, error) {
+			if prevStart != nil {
+				var err error
+				if ctx, err = prevStart(ctx); err != nil {
+					return ctx, err
+				}
+			}
+			ctx, cancel = context.WithCancel(ctx)
+			return context.WithValue(ctx, exitKey{}, cancel), nil
+		}
+
+		// The context is released when the Responder goroutine ends, whether or not an Exit fault occurred
+		prevEnd := o.GoPostEnd
+		o.GoPostEnd = func(err error) {
+			if cancel != nil {
+				cancel()
+			}
+			if prevEnd != nil {
+				prevEnd(err)
+			}
+		}
+	}
+}
+
+// Handler returns a Handler that applies the Injector's Faults before calling h.  A Drop fault holds
+// the Responder for its Duration, so use saferr.WithWorkers, or Requestor, to avoid delaying other requests
+func Handler[T any, U any](i *Injector[T], h types.Handler[T, U]) types.Handler[T, U] {
+	return func(ctx context.Context, t *T) (*U, error) {
+		for _, f := range i.faultsFor(t, true) {
+			switch f.Kind {
+			case Latency:
+				if err := i.wait(ctx, f.Duration); err != nil {
+					return nil, err
+				}
+			case Error:
+				if f.Err != nil {
+					return nil, f.Err
+				}
+				return nil, ErrInjected
+			case Panic:
+				panic("chaos: injected panic")
+			case Drop:
+				u, err := h(ctx, t)
+				i.wait(ctx, f.Duration)
+				return u, err
+			case Exit:
+				if cancel, ok := ctx.Value(exitKey{}).(context.CancelFunc); ok {
+					cancel()
+				}
+				return nil, ErrInjectedExit
+			}
+		}
+		return h(ctx, t)
+	}
+}
+
+type requestor[T any, U any] struct {
+	i *Injector[T]
+	r types.Requestor[T, U]
+}
+
+// Send applies the Faults on the caller side.  Panic and Exit faults only apply to Handlers,
+// and so are ignored
+func (r *requestor[T, U]) Send(ctx context.Context, t *T) (*U, error) {
+	for _, f := range r.i.faultsFor(t, false) {
+		switch f.Kind {
+		case Latency:
+			if err := r.i.wait(ctx, f.Duration); err != nil {
+				return nil, err
+			}
+		case Error:
+			if f.Err != nil {
+				return nil, f.Err
+			}
+			return nil, ErrInjected
+		case Drop:
+			// The request is processed, but the caller is told that it timed out
+			r.r.Send(ctx, t)
+			return nil, saferr.ErrSendTimeout
+		}
+	}
+	return r.r.Send(ctx, t)
+}
+
+// Requestor returns a Requestor that applies the Injector's Faults before calling r
+func Requestor[T any, U any](i *Injector[T], r types.Requestor[T, U]) types.Requestor[T, U] {
+	return &requestor[T, U]{i: i, r: r}
+}
@@ -0,0 +1,271 @@
+package chaos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gford1000-go/saferr"
+	"github.com/gford1000-go/saferr/mux"
+	"github.com/gford1000-go/saferr/types"
+)
+
+func reflect(ctx context.Context, i *int) (*int, error) {
+	return i, nil
+}
+
+func ExampleHandler() {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	injector := New(Config[int]{
+		Faults: []*Fault{
+			{Kind: Error, Schedule: Every(2)},
+			{Kind: Panic, Schedule: Nth(3)},
+		},
+	})
+
+	requestor := saferr.Go(ctx, Handler(injector, reflect))
+
+	for i := range 4 {
+		if response, err := requestor.Send(ctx, &i); err != nil {
+			fmt.Println(saferr.CodeOf(err))
+		} else {
+			fmt.Println(*response)
+		}
+	}
+
+	// Output:
+	// 0
+	// unavailable
+	// internal
+	// unavailable
+}
+
+func TestHandler_MuxKey(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m := mux.NewHandler[int, int, string](nil,
+		&mux.Register[int, int, string]{Key: "a", Handler: reflect},
+		&mux.Register[int, int, string]{Key: "b", Handler: reflect})
+
+	injector := New(Config[types.Request[int, string, string]]{
+		Faults: []*Fault{
+			{Kind: Error, Schedule: Always(), Keys: []string{"b"}},
+		},
+		KeyFunc: MuxKey[int, string, string](),
+	})
+
+	requestor := saferr.Go(ctx, Handler(injector, m.Handler))
+
+	v := 1
+	if _, err := requestor.Send(ctx, &types.Request[int, string, string]{Key: "a", Data: &v}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := requestor.Send(ctx, &types.Request[int, string, string]{Key: "b", Data: &v}); !errors.Is(err, ErrInjected) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := injector.Stats()[Error]; n != 1 {
+		t.Fatalf("expected 1 injected error, got %d", n)
+	}
+}
+
+func TestHandler_Probability(t *testing.T) {
+
+	run := func() []bool {
+		injector := New(Config[int]{
+			Faults: []*Fault{{Kind: Error, Probability: 0.5}},
+			Seed:   42,
+		})
+		h := Handler(injector, reflect)
+
+		var result []bool
+		for i := range 20 {
+			_, err := h(context.Background(), &i)
+			result = append(result, err != nil)
+		}
+		return result
+	}
+
+	// The same seed produces the same faults
+	a, b := run(), run()
+	failures := 0
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("faults differ at %d", i)
+		}
+		if a[i] {
+			failures++
+		}
+	}
+	if failures == 0 || failures == len(a) {
+		t.Fatalf("expected some requests to fail, got %d of %d", failures, len(a))
+	}
+}
+
+func TestHandler_Disabled(t *testing.T) {
+
+	injector := New(Config[int]{
+		Faults: []*Fault{{Kind: Panic, Schedule: Always()}},
+	})
+	injector.SetEnabled(false)
+
+	i := 1
+	if _, err := Handler(injector, reflect)(context.Background(), &i); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestHandler_Latency(t *testing.T) {
+
+	clock := saferr.NewFakeClock(time.Now())
+
+	injector := New(Config[int]{
+		Faults: []*Fault{{Kind: Latency, Schedule: Always(), Duration: time.Minute}},
+		Clock:  clock,
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		i := 1
+		Handler(injector, reflect)(context.Background(), &i)
+	}()
+
+	clock.BlockUntil(1)
+	select {
+	case <-done:
+		t.Fatal("handler should be delayed")
+	default:
+	}
+
+	clock.Advance(time.Minute)
+	<-done
+}
+
+func TestHandler_Drop(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	injector := New(Config[int]{
+		Faults: []*Fault{{Kind: Drop, Schedule: Nth(1), Duration: 200 * time.Millisecond}},
+	})
+
+	requestor := saferr.Go(ctx, Handler(injector, reflect),
+		saferr.WithRequestorTimeout(50*time.Millisecond))
+
+	i := 1
+	if _, err := requestor.Send(ctx, &i); !errors.Is(err, saferr.ErrSendTimeout) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The dropped response is discarded, and not received by the next request
+	<-time.After(200 * time.Millisecond)
+
+	j := 2
+	if response, err := requestor.Send(ctx, &j); err != nil || *response != j {
+		t.Fatalf("unexpected response: %v, %v", response, err)
+	}
+}
+
+func TestHandler_Exit(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	injector := New(Config[int]{
+		Faults: []*Fault{{Kind: Exit, Schedule: Nth(2)}},
+	})
+
+	exited := make(chan error, 1)
+	requestor := saferr.Go(ctx, Handler(injector, reflect),
+		saferr.WithRequestorTimeout(100*time.Millisecond),
+		saferr.WithGoPostEnd(func(err error) { exited <- err }),
+		injector.GoOption())
+
+	for n := range 2 {
+		_, err := requestor.Send(ctx, &n)
+		if n == 1 && !errors.Is(err, ErrInjectedExit) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if err := <-exited; !errors.Is(err, saferr.ErrContextCompleted) {
+		t.Fatalf("unexpected exit error: %v", err)
+	}
+}
+
+func TestRequestor(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	handled := 0
+	counter := func(ctx context.Context, i *int) (*int, error) {
+		handled++
+		return i, nil
+	}
+
+	injector := New(Config[int]{
+		Faults: []*Fault{
+			{Kind: Error, Schedule: Nth(1)},
+			{Kind: Drop, Schedule: Nth(2)},
+		},
+	})
+
+	requestor := Requestor(injector, saferr.Go(ctx, counter))
+
+	for n := range 3 {
+		_, err := requestor.Send(ctx, &n)
+		switch n {
+		case 0:
+			if !errors.Is(err, ErrInjected) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		case 1:
+			if !errors.Is(err, saferr.ErrSendTimeout) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		default:
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+	}
+
+	// Errors are not sent to the Responder, whereas dropped requests are
+	if handled != 2 {
+		t.Fatalf("expected 2 requests to be handled, got %d", handled)
+	}
+}
+
+func TestRequestor_HandlerFaults(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	injector := New(Config[int]{
+		Faults: []*Fault{
+			{Kind: Panic, Schedule: Always()},
+			{Kind: Exit, Schedule: Always()},
+		},
+	})
+
+	requestor := Requestor(injector, saferr.Go(ctx, func(ctx context.Context, i *int) (*int, error) { return i, nil }))
+
+	i := 1
+	if _, err := requestor.Send(ctx, &i); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Faults that only apply to Handlers are not counted
+	if stats := injector.Stats(); len(stats) != 0 {
+		t.Fatalf("expected no faults to be counted, got %v", stats)
+	}
+}
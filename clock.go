@@ -0,0 +1,193 @@
+package saferr
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Timer is the subset of time.Timer behaviour used by this package, allowing it to be
+// provided by a Clock
+type Timer interface {
+	// C returns the chan on which the time is delivered when the Timer fires
+	C() <-chan time.Time
+	// Stop prevents the Timer from firing, returning false if it has already fired or been stopped
+	Stop() bool
+	// Reset changes the Timer to fire after duration d, returning true if it had been active
+	Reset(d time.Duration) bool
+}
+
+// Clock provides the current time, timers and sleeping, so that time dependent behaviour
+// (timeouts, detection of gone away Requestors etc.) can be controlled in tests
+type Clock interface {
+	// Now returns the current time
+	Now() time.Time
+	// NewTimer returns a Timer that will fire after duration d
+	NewTimer(d time.Duration) Timer
+	// Sleep pauses the calling goroutine for duration d
+	Sleep(d time.Duration)
+}
+
+// RealClock returns the Clock that uses the wall clock, which is the default Clock
+func RealClock() Clock {
+	return realClock{}
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) NewTimer(d time.Duration) Timer { return &realTimer{t: time.NewTimer(d)} }
+
+func (realClock) Sleep(d time.Duration) { time.Sleep(d) }
+
+type realTimer struct {
+	t *time.Timer
+}
+
+func (r *realTimer) C() <-chan time.Time { return r.t.C }
+
+func (r *realTimer) Stop() bool { return r.t.Stop() }
+
+func (r *realTimer) Reset(d time.Duration) bool { return r.t.Reset(d) }
+
+// FakeClock is a Clock whose time only moves when Advance is called, allowing
+// timeouts to be tested instantly and reproducibly
+type FakeClock struct {
+	lck    sync.Mutex
+	cond   *sync.Cond
+	now    time.Time
+	timers []*fakeTimer
+}
+
+// NewFakeClock returns a FakeClock with its time set to start
+func NewFakeClock(start time.Time) *FakeClock {
+	c := &FakeClock{now: start}
+	c.cond = sync.NewCond(&c.lck)
+	return c
+}
+
+// Now returns the current time of the FakeClock
+func (c *FakeClock) Now() time.Time {
+	c.lck.Lock()
+	defer c.lck.Unlock()
+	return c.now
+}
+
+// NewTimer returns a Timer that fires once the FakeClock has been advanced by at least d
+func (c *FakeClock) NewTimer(d time.Duration) Timer {
+	t := &fakeTimer{
+		clock: c,
+		ch:    make(chan time.Time, 1),
+	}
+	t.Reset(d)
+	return t
+}
+
+// Sleep blocks until the FakeClock has been advanced by at least d
+func (c *FakeClock) Sleep(d time.Duration) {
+	t := c.NewTimer(d)
+	<-t.C()
+}
+
+// Advance moves the time of the FakeClock forward by d, firing any Timers that
+// are now due, in the order of their deadlines
+func (c *FakeClock) Advance(d time.Duration) {
+	c.lck.Lock()
+	defer c.lck.Unlock()
+
+	c.now = c.now.Add(d)
+
+	sort.SliceStable(c.timers, func(i, j int) bool {
+		return c.timers[i].deadline.Before(c.timers[j].deadline)
+	})
+
+	remaining := c.timers[:0]
+	for _, t := range c.timers {
+		if t.deadline.After(c.now) {
+			remaining = append(remaining, t)
+			continue
+		}
+		t.active = false
+		select {
+		case t.ch <- c.now:
+		default:
+		}
+	}
+	clear(c.timers[len(remaining):])
+	c.timers = remaining
+	c.cond.Broadcast()
+}
+
+// Timers returns the number of active Timers
+func (c *FakeClock) Timers() int {
+	c.lck.Lock()
+	defer c.lck.Unlock()
+	return len(c.timers)
+}
+
+// BlockUntil blocks until there are at least n active Timers, which allows tests to
+// wait for a goroutine to start waiting on the FakeClock before calling Advance
+func (c *FakeClock) BlockUntil(n int) {
+	c.lck.Lock()
+	defer c.lck.Unlock()
+	for len(c.timers) < n {
+		c.cond.Wait()
+	}
+}
+
+type fakeTimer struct {
+	clock    *FakeClock
+	ch       chan time.Time
+	deadline time.Time
+	active   bool
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.ch }
+
+func (t *fakeTimer) Stop() bool {
+	c := t.clock
+	c.lck.Lock()
+	defer c.lck.Unlock()
+	return c.remove(t)
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	c := t.clock
+	c.lck.Lock()
+	defer c.lck.Unlock()
+
+	wasActive := c.remove(t)
+
+	// Mirror time.Timer (Go 1.23+), where Reset discards any undelivered value
+	select {
+	case <-t.ch:
+	default:
+	}
+
+	t.deadline = c.now.Add(d)
+	if d <= 0 {
+		t.ch <- c.now
+	} else {
+		t.active = true
+		c.timers = append(c.timers, t)
+	}
+	c.cond.Broadcast()
+	return wasActive
+}
+
+// remove must be called with the lock held
+func (c *FakeClock) remove(t *fakeTimer) bool {
+	if !t.active {
+		return false
+	}
+	t.active = false
+	for i, v := range c.timers {
+		if v == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			break
+		}
+	}
+	c.cond.Broadcast()
+	return true
+}
@@ -0,0 +1,126 @@
+package saferr
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// advanceUntil advances the clock by d whenever a timer is waiting, until done is closed
+func advanceUntil(c *FakeClock, d time.Duration, done <-chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		if c.Timers() > 0 {
+			c.Advance(d)
+		}
+		runtime.Gosched()
+	}
+}
+
+func TestFakeClock(t *testing.T) {
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewFakeClock(start)
+
+	t1 := c.NewTimer(time.Second)
+	t2 := c.NewTimer(2 * time.Second)
+
+	c.Advance(time.Second)
+
+	select {
+	case v := <-t1.C():
+		if !v.Equal(start.Add(time.Second)) {
+			t.Fatalf("unexpected fire time: %v", v)
+		}
+	default:
+		t.Fatal("t1 should have fired")
+	}
+
+	select {
+	case <-t2.C():
+		t.Fatal("t2 should not have fired")
+	default:
+	}
+
+	if !t2.Stop() {
+		t.Fatal("t2 should have been active")
+	}
+	if c.Timers() != 0 {
+		t.Fatalf("expected no active timers, got %d", c.Timers())
+	}
+}
+
+func TestFakeClock_RequestorTimeout(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	clock := NewFakeClock(time.Now())
+	start := clock.Now()
+
+	// No Responder is listening, so the Send can only complete via its timeout
+	requestor, _ := New[int, int](ctx,
+		WithClock(clock),
+		WithRequestorTimeout(time.Hour))
+
+	var err error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		input := 42
+		_, err = requestor.Send(ctx, &input)
+	}()
+
+	advanceUntil(clock, time.Hour, done)
+
+	if !errors.Is(err, ErrSendTimeout) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if clock.Now().Sub(start) < time.Hour {
+		t.Fatalf("Send should not timeout before RequestorTimeout, elapsed: %v", clock.Now().Sub(start))
+	}
+}
+
+func TestFakeClock_RequestorGoneAwayTimeout(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	clock := NewFakeClock(time.Now())
+
+	_, receiver := New[int, int](ctx,
+		WithClock(clock),
+		WithResponderTimeout(time.Second),
+		WithRequestorGoneWayTimeout(time.Minute))
+
+	reflect := func(ctx context.Context, input *int) (*int, error) {
+		return input, nil
+	}
+
+	errs := make(chan error, 1)
+	listen := func() {
+		errs <- receiver.ListenAndHandle(ctx, reflect)
+	}
+
+	// Before the gone away timeout, ListenAndHandle returns nil after its ResponderTimeout
+	go listen()
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	if err := <-errs; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Once the gone away timeout has passed, the Responder closes
+	go listen()
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	if err := <-errs; !errors.Is(err, ErrRequestorGoneAway) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,35 @@
+// Package example demonstrates the code generated by saferr-gen
+package example
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+//go:generate go run .. -type Calculator
+
+// Calculator is the definition of the service
+type Calculator interface {
+	Square(ctx context.Context, x int) (int, error)
+	Add(ctx context.Context, a, b int) (int, error)
+	Sum(ctx context.Context, values ...float64) (float64, error)
+	DivMod(ctx context.Context, a, b int) (quotient, remainder int, err error)
+	Describe(ctx context.Context, req *DescribeRequest) (*Description, error)
+	Wait(ctx context.Context, d time.Duration) error
+	Reset(ctx context.Context) error
+}
+
+// DescribeRequest is the request for Calculator.Describe
+type DescribeRequest struct {
+	Value     float64
+	Precision int
+}
+
+// Description is the response for Calculator.Describe
+type Description struct {
+	Text string
+}
+
+// ErrDivideByZero is returned by DivMod if b is zero
+var ErrDivideByZero = errors.New("divide by zero")
@@ -0,0 +1,201 @@
+// Code generated by saferr-gen; DO NOT EDIT.
+
+package example
+
+import (
+	"context"
+	"time"
+
+	"github.com/gford1000-go/saferr/mux"
+	"github.com/gford1000-go/saferr/types"
+)
+
+// Keys of the routes of Calculator
+const (
+	CalculatorSquareKey   = "Calculator.Square"
+	CalculatorAddKey      = "Calculator.Add"
+	CalculatorSumKey      = "Calculator.Sum"
+	CalculatorDivModKey   = "Calculator.DivMod"
+	CalculatorDescribeKey = "Calculator.Describe"
+	CalculatorWaitKey     = "Calculator.Wait"
+	CalculatorResetKey    = "Calculator.Reset"
+)
+
+// Routes of Calculator, with the request and response types of each
+var (
+	CalculatorSquareRoute   = mux.NewRouteKey[int, int](CalculatorSquareKey)
+	CalculatorAddRoute      = mux.NewRouteKey[CalculatorAddRequest, int](CalculatorAddKey)
+	CalculatorSumRoute      = mux.NewRouteKey[CalculatorSumRequest, float64](CalculatorSumKey)
+	CalculatorDivModRoute   = mux.NewRouteKey[CalculatorDivModRequest, CalculatorDivModResponse](CalculatorDivModKey)
+	CalculatorDescribeRoute = mux.NewRouteKey[DescribeRequest, Description](CalculatorDescribeKey)
+	CalculatorWaitRoute     = mux.NewRouteKey[time.Duration, CalculatorWaitResponse](CalculatorWaitKey)
+	CalculatorResetRoute    = mux.NewRouteKey[CalculatorResetRequest, CalculatorResetResponse](CalculatorResetKey)
+)
+
+// CalculatorAddRequest is the request envelope for Calculator.Add
+type CalculatorAddRequest struct {
+	A int
+	B int
+}
+
+// CalculatorSumRequest is the request envelope for Calculator.Sum
+type CalculatorSumRequest struct {
+	Values []float64
+}
+
+// CalculatorDivModRequest is the request envelope for Calculator.DivMod
+type CalculatorDivModRequest struct {
+	A int
+	B int
+}
+
+// CalculatorDivModResponse is the response envelope for Calculator.DivMod
+type CalculatorDivModResponse struct {
+	Quotient  int
+	Remainder int
+}
+
+// CalculatorWaitResponse is the response envelope for Calculator.Wait
+type CalculatorWaitResponse struct{}
+
+// CalculatorResetRequest is the request envelope for Calculator.Reset
+type CalculatorResetRequest struct{}
+
+// CalculatorResetResponse is the response envelope for Calculator.Reset
+type CalculatorResetResponse struct{}
+
+// RegisterCalculator adds a route to th for each method of impl
+func RegisterCalculator[M any](th *mux.TypedHandler[M, string], impl Calculator) error {
+	if _, err := mux.Route(th, CalculatorSquareKey, func(ctx context.Context, t *int) (*int, error) {
+		var v int
+		if t != nil {
+			v = *t
+		}
+		r0, err := impl.Square(ctx, v)
+		return &r0, err
+	}); err != nil {
+		return err
+	}
+	if _, err := mux.Route(th, CalculatorAddKey, func(ctx context.Context, t *CalculatorAddRequest) (*int, error) {
+		if t == nil {
+			t = &CalculatorAddRequest{}
+		}
+		r0, err := impl.Add(ctx, t.A, t.B)
+		return &r0, err
+	}); err != nil {
+		return err
+	}
+	if _, err := mux.Route(th, CalculatorSumKey, func(ctx context.Context, t *CalculatorSumRequest) (*float64, error) {
+		if t == nil {
+			t = &CalculatorSumRequest{}
+		}
+		r0, err := impl.Sum(ctx, t.Values...)
+		return &r0, err
+	}); err != nil {
+		return err
+	}
+	if _, err := mux.Route(th, CalculatorDivModKey, func(ctx context.Context, t *CalculatorDivModRequest) (*CalculatorDivModResponse, error) {
+		if t == nil {
+			t = &CalculatorDivModRequest{}
+		}
+		r0, r1, err := impl.DivMod(ctx, t.A, t.B)
+		return &CalculatorDivModResponse{Quotient: r0, Remainder: r1}, err
+	}); err != nil {
+		return err
+	}
+	if _, err := mux.Route(th, CalculatorDescribeKey, func(ctx context.Context, t *DescribeRequest) (*Description, error) {
+		return impl.Describe(ctx, t)
+	}); err != nil {
+		return err
+	}
+	if _, err := mux.Route(th, CalculatorWaitKey, func(ctx context.Context, t *time.Duration) (*CalculatorWaitResponse, error) {
+		var v time.Duration
+		if t != nil {
+			v = *t
+		}
+		err := impl.Wait(ctx, v)
+		return &CalculatorWaitResponse{}, err
+	}); err != nil {
+		return err
+	}
+	if _, err := mux.Route(th, CalculatorResetKey, func(ctx context.Context, t *CalculatorResetRequest) (*CalculatorResetResponse, error) {
+		err := impl.Reset(ctx)
+		return &CalculatorResetResponse{}, err
+	}); err != nil {
+		return err
+	}
+	return nil
+}
+
+// CalculatorClient implements Calculator by sending Requests to a Responder created using RegisterCalculator
+type CalculatorClient[M any] struct {
+	// Requestor used to send the Requests
+	Requestor types.Requestor[mux.TypedRequest[M, string], any]
+	// Meta sent with each Request
+	Meta M
+}
+
+// NewCalculatorClient creates a CalculatorClient that uses requestor
+func NewCalculatorClient[M any](requestor types.Requestor[mux.TypedRequest[M, string], any]) *CalculatorClient[M] {
+	return &CalculatorClient[M]{Requestor: requestor}
+}
+
+// Square calls Calculator.Square using the Requestor
+func (c *CalculatorClient[M]) Square(ctx context.Context, x int) (int, error) {
+	u, err := mux.CallWithMeta(ctx, c.Requestor, CalculatorSquareRoute, c.Meta, &x)
+	if u == nil {
+		var r0 int
+		return r0, err
+	}
+	return *u, err
+}
+
+// Add calls Calculator.Add using the Requestor
+func (c *CalculatorClient[M]) Add(ctx context.Context, a int, b int) (int, error) {
+	u, err := mux.CallWithMeta(ctx, c.Requestor, CalculatorAddRoute, c.Meta, &CalculatorAddRequest{A: a, B: b})
+	if u == nil {
+		var r0 int
+		return r0, err
+	}
+	return *u, err
+}
+
+// Sum calls Calculator.Sum using the Requestor
+func (c *CalculatorClient[M]) Sum(ctx context.Context, values ...float64) (float64, error) {
+	u, err := mux.CallWithMeta(ctx, c.Requestor, CalculatorSumRoute, c.Meta, &CalculatorSumRequest{Values: values})
+	if u == nil {
+		var r0 float64
+		return r0, err
+	}
+	return *u, err
+}
+
+// DivMod calls Calculator.DivMod using the Requestor
+func (c *CalculatorClient[M]) DivMod(ctx context.Context, a int, b int) (int, int, error) {
+	u, err := mux.CallWithMeta(ctx, c.Requestor, CalculatorDivModRoute, c.Meta, &CalculatorDivModRequest{A: a, B: b})
+	if u == nil {
+		var r0 int
+		var r1 int
+		return r0, r1, err
+	}
+	return u.Quotient, u.Remainder, err
+}
+
+// Describe calls Calculator.Describe using the Requestor
+func (c *CalculatorClient[M]) Describe(ctx context.Context, req *DescribeRequest) (*Description, error) {
+	return mux.CallWithMeta(ctx, c.Requestor, CalculatorDescribeRoute, c.Meta, req)
+}
+
+// Wait calls Calculator.Wait using the Requestor
+func (c *CalculatorClient[M]) Wait(ctx context.Context, d time.Duration) error {
+	_, err := mux.CallWithMeta(ctx, c.Requestor, CalculatorWaitRoute, c.Meta, &d)
+	return err
+}
+
+// Reset calls Calculator.Reset using the Requestor
+func (c *CalculatorClient[M]) Reset(ctx context.Context) error {
+	_, err := mux.CallWithMeta(ctx, c.Requestor, CalculatorResetRoute, c.Meta, &CalculatorResetRequest{})
+	return err
+}
+
+var _ Calculator = (*CalculatorClient[struct{}])(nil)
@@ -0,0 +1,115 @@
+package example
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gford1000-go/saferr"
+	"github.com/gford1000-go/saferr/mux"
+)
+
+type calculator struct {
+	resets int
+}
+
+func (c *calculator) Square(ctx context.Context, x int) (int, error) { return x * x, nil }
+
+func (c *calculator) Add(ctx context.Context, a, b int) (int, error) { return a + b, nil }
+
+func (c *calculator) Sum(ctx context.Context, values ...float64) (float64, error) {
+	var total float64
+	for _, v := range values {
+		total += v
+	}
+	return total, nil
+}
+
+func (c *calculator) DivMod(ctx context.Context, a, b int) (int, int, error) {
+	if b == 0 {
+		return 0, 0, ErrDivideByZero
+	}
+	return a / b, a % b, nil
+}
+
+func (c *calculator) Describe(ctx context.Context, req *DescribeRequest) (*Description, error) {
+	return &Description{Text: strconv.FormatFloat(req.Value, 'f', req.Precision, 64)}, nil
+}
+
+func (c *calculator) Wait(ctx context.Context, d time.Duration) error {
+	select {
+	case <-time.After(d):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *calculator) Reset(ctx context.Context) error {
+	c.resets++
+	return nil
+}
+
+func newClient(ctx context.Context, impl Calculator) (Calculator, error) {
+	th := mux.NewTypedHandler[string, string](nil)
+	if err := RegisterCalculator(th, impl); err != nil {
+		return nil, err
+	}
+	return NewCalculatorClient(saferr.Go(ctx, th.Handler)), nil
+}
+
+func ExampleCalculatorClient() {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	th := mux.NewTypedHandler[string, string](nil)
+	if err := RegisterCalculator(th, &calculator{}); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	var client Calculator = NewCalculatorClient(saferr.Go(ctx, th.Handler))
+
+	sum, _ := client.Add(ctx, 3, 4)
+	q, r, _ := client.DivMod(ctx, 17, 5)
+
+	fmt.Println(sum, q, r)
+
+	// Output:
+	// 7 3 2
+}
+
+func TestCalculatorClient(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	impl := &calculator{}
+	client, err := newClient(ctx, impl)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if v, err := client.Square(ctx, 9); err != nil || v != 81 {
+		t.Fatalf("Square: %v, %v", v, err)
+	}
+	if v, err := client.Sum(ctx, 1.5, 2.5, 3); err != nil || v != 7 {
+		t.Fatalf("Sum: %v, %v", v, err)
+	}
+	if _, _, err := client.DivMod(ctx, 1, 0); !errors.Is(err, ErrDivideByZero) {
+		t.Fatalf("DivMod: expected ErrDivideByZero, got %v", err)
+	}
+	if d, err := client.Describe(ctx, &DescribeRequest{Value: 3.14159, Precision: 2}); err != nil || d.Text != "3.14" {
+		t.Fatalf("Describe: %v, %v", d, err)
+	}
+	if err := client.Wait(ctx, time.Millisecond); err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+	if err := client.Reset(ctx); err != nil || impl.resets != 1 {
+		t.Fatalf("Reset: %v, %d", err, impl.resets)
+	}
+}
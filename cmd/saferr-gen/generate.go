@@ -0,0 +1,502 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// errUnsupported is returned if an interface or method cannot be generated
+var errUnsupported = errors.New("unsupported")
+
+// param is a parameter or result of a method, other than the context and the error
+type param struct {
+	// Name used for the parameter in the generated client method
+	Name string
+	// Field used for the parameter in an envelope
+	Field string
+	// Type of the parameter, as written in the source
+	Type     string
+	Variadic bool
+}
+
+// method describes how a method of the interface is mapped to a route
+type method struct {
+	Name    string
+	Params  []param
+	Results []param
+
+	// Request and Response are the types T and U used with mux.Route and mux.Call
+	Request  string
+	Response string
+}
+
+// requestEnvelope returns true if the parameters are passed in a generated request envelope
+func (m *method) requestEnvelope() bool {
+	return len(m.Params) != 1 || m.Params[0].Variadic
+}
+
+// responseEnvelope returns true if the results are returned in a generated response envelope
+func (m *method) responseEnvelope() bool {
+	return len(m.Results) != 1
+}
+
+// service describes an interface for which code is generated
+type service struct {
+	Name    string
+	Methods []*method
+}
+
+// generator accumulates the code generated for the interfaces of a single file
+type generator struct {
+	pkg      string
+	imports  map[string]string // name -> path, for the imports of the source file
+	used     map[string]bool   // paths of the imports used by the generated code
+	services []*service
+}
+
+// generate returns the formatted code for the named interfaces declared in src
+func generate(filename string, src []byte, names []string) ([]byte, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, src, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &generator{
+		pkg:     f.Name.Name,
+		imports: map[string]string{},
+		used:    map[string]bool{},
+	}
+
+	for _, imp := range f.Imports {
+		p, _ := strconv.Unquote(imp.Path.Value)
+		name := importName(p)
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+		g.imports[name] = p
+	}
+
+	for _, name := range names {
+		it := findInterface(f, name)
+		if it == nil {
+			return nil, fmt.Errorf("interface %s not found in %s", name, filename)
+		}
+		s, err := g.parseService(name, it)
+		if err != nil {
+			return nil, err
+		}
+		g.services = append(g.services, s)
+	}
+
+	return g.write()
+}
+
+var versionSuffix = regexp.MustCompile(`^v[0-9]+$`)
+
+// importName returns the default name of the package with the import path p
+func importName(p string) string {
+	name := path.Base(p)
+	if versionSuffix.MatchString(name) && path.Dir(p) != "." {
+		name = path.Base(path.Dir(p))
+	}
+	return strings.ReplaceAll(name, "-", "_")
+}
+
+func findInterface(f *ast.File, name string) *ast.InterfaceType {
+	for _, d := range f.Decls {
+		gd, ok := d.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			ts := spec.(*ast.TypeSpec)
+			if ts.Name.Name != name {
+				continue
+			}
+			if it, ok := ts.Type.(*ast.InterfaceType); ok {
+				return it
+			}
+		}
+	}
+	return nil
+}
+
+func (g *generator) parseService(name string, it *ast.InterfaceType) (*service, error) {
+	s := &service{Name: name}
+
+	for _, field := range it.Methods.List {
+		ft, ok := field.Type.(*ast.FuncType)
+		if !ok || len(field.Names) == 0 {
+			return nil, fmt.Errorf("%w: %s embeds %s", errUnsupported, name, types.ExprString(field.Type))
+		}
+		m, err := g.parseMethod(name, field.Names[0].Name, ft)
+		if err != nil {
+			return nil, err
+		}
+		s.Methods = append(s.Methods, m)
+	}
+
+	if len(s.Methods) == 0 {
+		return nil, fmt.Errorf("%w: %s has no methods", errUnsupported, name)
+	}
+	return s, nil
+}
+
+// reserved names are used by the generated client methods, and so cannot be used for parameters
+var reserved = map[string]bool{"c": true, "ctx": true, "u": true, "err": true}
+
+func (g *generator) parseMethod(svc, name string, ft *ast.FuncType) (*method, error) {
+	m := &method{Name: name}
+
+	params := flatten(ft.Params)
+	if len(params) == 0 || types.ExprString(params[0].typ) != "context.Context" {
+		return nil, fmt.Errorf("%w: %s.%s must have a context.Context as its first parameter", errUnsupported, svc, name)
+	}
+	g.use(params[0].typ)
+
+	results := flatten(ft.Results)
+	if len(results) == 0 || types.ExprString(results[len(results)-1].typ) != "error" {
+		return nil, fmt.Errorf("%w: %s.%s must have an error as its last result", errUnsupported, svc, name)
+	}
+
+	for i, p := range params[1:] {
+		g.use(p.typ)
+		prm := param{Name: p.name, Field: field(p.name, "P", i), Type: types.ExprString(p.typ)}
+		if e, ok := p.typ.(*ast.Ellipsis); ok {
+			prm.Variadic = true
+			prm.Type = types.ExprString(e.Elt)
+		}
+		if prm.Name == "" || prm.Name == "_" || reserved[prm.Name] {
+			prm.Name = fmt.Sprintf("p%d", i)
+		}
+		m.Params = append(m.Params, prm)
+	}
+
+	for i, r := range results[:len(results)-1] {
+		g.use(r.typ)
+		m.Results = append(m.Results, param{Name: fmt.Sprintf("r%d", i), Field: field(r.name, "R", i), Type: types.ExprString(r.typ)})
+	}
+
+	if m.requestEnvelope() {
+		m.Request = svc + name + "Request"
+	} else {
+		m.Request = strings.TrimPrefix(m.Params[0].Type, "*")
+	}
+
+	if m.responseEnvelope() {
+		m.Response = svc + name + "Response"
+	} else {
+		m.Response = strings.TrimPrefix(m.Results[0].Type, "*")
+	}
+
+	return m, nil
+}
+
+type namedExpr struct {
+	name string
+	typ  ast.Expr
+}
+
+// flatten returns each parameter in the list separately, so that "a, b int" becomes "a int, b int"
+func flatten(fl *ast.FieldList) []namedExpr {
+	if fl == nil {
+		return nil
+	}
+	var result []namedExpr
+	for _, f := range fl.List {
+		if len(f.Names) == 0 {
+			result = append(result, namedExpr{typ: f.Type})
+			continue
+		}
+		for _, n := range f.Names {
+			result = append(result, namedExpr{name: n.Name, typ: f.Type})
+		}
+	}
+	return result
+}
+
+// field returns the exported envelope field name for a parameter or result
+func field(name, prefix string, i int) string {
+	if name == "" || name == "_" {
+		return fmt.Sprintf("%s%d", prefix, i)
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// use records the imports needed for the type expression e
+func (g *generator) use(e ast.Expr) {
+	ast.Inspect(e, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if id, ok := sel.X.(*ast.Ident); ok {
+				if p, ok := g.imports[id.Name]; ok {
+					g.used[p] = true
+				}
+			}
+		}
+		return true
+	})
+}
+
+func (g *generator) write() ([]byte, error) {
+	var b bytes.Buffer
+
+	fmt.Fprintf(&b, "// Code generated by saferr-gen; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&b, "package %s\n\n", g.pkg)
+
+	g.used["context"] = true
+	g.used["github.com/gford1000-go/saferr/mux"] = true
+	g.used["github.com/gford1000-go/saferr/types"] = true
+
+	var paths []string
+	for p := range g.used {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	aliases := map[string]string{}
+	for name, p := range g.imports {
+		if name != importName(p) {
+			aliases[p] = name
+		}
+	}
+
+	// Standard library imports first, then the others
+	b.WriteString("import (\n")
+	for _, std := range []bool{true, false} {
+		if !std {
+			b.WriteString("\n")
+		}
+		for _, p := range paths {
+			if isStd(p) == std {
+				fmt.Fprintf(&b, "\t%s%q\n", prefixed(aliases[p]), p)
+			}
+		}
+	}
+	b.WriteString(")\n")
+
+	for _, s := range g.services {
+		g.writeService(&b, s)
+	}
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %w\n%s", err, b.Bytes())
+	}
+	return src, nil
+}
+
+// isStd returns true if p is the import path of a standard library package
+func isStd(p string) bool {
+	return !strings.Contains(strings.Split(p, "/")[0], ".")
+}
+
+func prefixed(alias string) string {
+	if alias == "" {
+		return ""
+	}
+	return alias + " "
+}
+
+func (g *generator) writeService(b *bytes.Buffer, s *service) {
+
+	// Keys
+	fmt.Fprintf(b, "\n// Keys of the routes of %s\nconst (\n", s.Name)
+	for _, m := range s.Methods {
+		fmt.Fprintf(b, "\t%s = %q\n", keyConst(s, m), s.Name+"."+m.Name)
+	}
+	b.WriteString(")\n")
+
+	// Typed keys
+	fmt.Fprintf(b, "\n// Routes of %s, with the request and response types of each\nvar (\n", s.Name)
+	for _, m := range s.Methods {
+		fmt.Fprintf(b, "\t%s = mux.NewRouteKey[%s, %s](%s)\n", routeVar(s, m), m.Request, m.Response, keyConst(s, m))
+	}
+	b.WriteString(")\n")
+
+	// Envelopes
+	for _, m := range s.Methods {
+		if m.requestEnvelope() {
+			var fields []string
+			for _, p := range m.Params {
+				fields = append(fields, fmt.Sprintf("\t%s %s\n", p.Field, envelopeType(p)))
+			}
+			writeEnvelope(b, "request", m.Request, s.Name+"."+m.Name, fields)
+		}
+		if m.responseEnvelope() {
+			var fields []string
+			for _, r := range m.Results {
+				fields = append(fields, fmt.Sprintf("\t%s %s\n", r.Field, r.Type))
+			}
+			writeEnvelope(b, "response", m.Response, s.Name+"."+m.Name, fields)
+		}
+	}
+
+	// Server
+	fmt.Fprintf(b, "\n// Register%s adds a route to th for each method of impl\n", s.Name)
+	fmt.Fprintf(b, "func Register%s[M any](th *mux.TypedHandler[M, string], impl %s) error {\n", s.Name, s.Name)
+	for _, m := range s.Methods {
+		fmt.Fprintf(b, "\tif _, err := mux.Route(th, %s, func(ctx context.Context, t *%s) (*%s, error) {\n", keyConst(s, m), m.Request, m.Response)
+		g.writeServerBody(b, m)
+		b.WriteString("\t}); err != nil {\n\t\treturn err\n\t}\n")
+	}
+	b.WriteString("\treturn nil\n}\n")
+
+	// Client
+	client := s.Name + "Client"
+	fmt.Fprintf(b, "\n// %s implements %s by sending Requests to a Responder created using Register%s\n", client, s.Name, s.Name)
+	fmt.Fprintf(b, "type %s[M any] struct {\n", client)
+	b.WriteString("\t// Requestor used to send the Requests\n")
+	b.WriteString("\tRequestor types.Requestor[mux.TypedRequest[M, string], any]\n")
+	b.WriteString("\t// Meta sent with each Request\n")
+	b.WriteString("\tMeta M\n}\n")
+
+	fmt.Fprintf(b, "\n// New%s creates a %s that uses requestor\n", client, client)
+	fmt.Fprintf(b, "func New%s[M any](requestor types.Requestor[mux.TypedRequest[M, string], any]) *%s[M] {\n", client, client)
+	fmt.Fprintf(b, "\treturn &%s[M]{Requestor: requestor}\n}\n", client)
+
+	for _, m := range s.Methods {
+		g.writeClientMethod(b, s, m)
+	}
+
+	fmt.Fprintf(b, "\nvar _ %s = (*%s[struct{}])(nil)\n", s.Name, client)
+}
+
+func writeEnvelope(b *bytes.Buffer, kind, name, method string, fields []string) {
+	fmt.Fprintf(b, "\n// %s is the %s envelope for %s\n", name, kind, method)
+	if len(fields) == 0 {
+		fmt.Fprintf(b, "type %s struct{}\n", name)
+		return
+	}
+	fmt.Fprintf(b, "type %s struct {\n%s}\n", name, strings.Join(fields, ""))
+}
+
+func keyConst(s *service, m *method) string {
+	return s.Name + m.Name + "Key"
+}
+
+func routeVar(s *service, m *method) string {
+	return s.Name + m.Name + "Route"
+}
+
+func envelopeType(p param) string {
+	if p.Variadic {
+		return "[]" + p.Type
+	}
+	return p.Type
+}
+
+func (g *generator) writeServerBody(b *bytes.Buffer, m *method) {
+	var args []string
+	switch {
+	case m.requestEnvelope():
+		if len(m.Params) > 0 {
+			fmt.Fprintf(b, "\t\tif t == nil {\n\t\t\tt = &%s{}\n\t\t}\n", m.Request)
+		}
+		for _, p := range m.Params {
+			arg := "t." + p.Field
+			if p.Variadic {
+				arg += "..."
+			}
+			args = append(args, arg)
+		}
+	case strings.HasPrefix(m.Params[0].Type, "*"):
+		args = append(args, "t")
+	default:
+		fmt.Fprintf(b, "\t\tvar v %s\n\t\tif t != nil {\n\t\t\tv = *t\n\t\t}\n", m.Params[0].Type)
+		args = append(args, "v")
+	}
+
+	call := fmt.Sprintf("impl.%s(%s)", m.Name, strings.Join(append([]string{"ctx"}, args...), ", "))
+
+	switch {
+	case m.responseEnvelope():
+		var names, fields []string
+		for _, r := range m.Results {
+			names = append(names, r.Name)
+			fields = append(fields, fmt.Sprintf("%s: %s", r.Field, r.Name))
+		}
+		fmt.Fprintf(b, "\t\t%s := %s\n", strings.Join(append(names, "err"), ", "), call)
+		fmt.Fprintf(b, "\t\treturn &%s{%s}, err\n", m.Response, strings.Join(fields, ", "))
+	case strings.HasPrefix(m.Results[0].Type, "*"):
+		fmt.Fprintf(b, "\t\treturn %s\n", call)
+	default:
+		fmt.Fprintf(b, "\t\tr0, err := %s\n\t\treturn &r0, err\n", call)
+	}
+}
+
+func (g *generator) writeClientMethod(b *bytes.Buffer, s *service, m *method) {
+	var params []string
+	for _, p := range m.Params {
+		t := p.Type
+		if p.Variadic {
+			t = "..." + t
+		}
+		params = append(params, p.Name+" "+t)
+	}
+
+	var results []string
+	for _, r := range m.Results {
+		results = append(results, r.Type)
+	}
+	results = append(results, "error")
+
+	resultList := strings.Join(results, ", ")
+	if len(results) > 1 {
+		resultList = "(" + resultList + ")"
+	}
+
+	fmt.Fprintf(b, "\n// %s calls %s.%s using the Requestor\n", m.Name, s.Name, m.Name)
+	fmt.Fprintf(b, "func (c *%sClient[M]) %s(%s) %s {\n",
+		s.Name, m.Name, strings.Join(append([]string{"ctx context.Context"}, params...), ", "), resultList)
+
+	var arg string
+	switch {
+	case m.requestEnvelope():
+		var fields []string
+		for _, p := range m.Params {
+			fields = append(fields, fmt.Sprintf("%s: %s", p.Field, p.Name))
+		}
+		arg = fmt.Sprintf("&%s{%s}", m.Request, strings.Join(fields, ", "))
+	case strings.HasPrefix(m.Params[0].Type, "*"):
+		arg = m.Params[0].Name
+	default:
+		arg = "&" + m.Params[0].Name
+	}
+
+	call := fmt.Sprintf("mux.CallWithMeta(ctx, c.Requestor, %s, c.Meta, %s)", routeVar(s, m), arg)
+
+	switch {
+	case len(m.Results) == 0:
+		fmt.Fprintf(b, "\t_, err := %s\n\treturn err\n", call)
+	case m.responseEnvelope():
+		var zeros, fields []string
+		for _, r := range m.Results {
+			zeros = append(zeros, fmt.Sprintf("\t\tvar %s %s\n", r.Name, r.Type))
+			fields = append(fields, "u."+r.Field)
+		}
+		var names []string
+		for _, r := range m.Results {
+			names = append(names, r.Name)
+		}
+		fmt.Fprintf(b, "\tu, err := %s\n\tif u == nil {\n%s\t\treturn %s, err\n\t}\n", call, strings.Join(zeros, ""), strings.Join(names, ", "))
+		fmt.Fprintf(b, "\treturn %s, err\n", strings.Join(fields, ", "))
+	case strings.HasPrefix(m.Results[0].Type, "*"):
+		fmt.Fprintf(b, "\treturn %s\n", call)
+	default:
+		fmt.Fprintf(b, "\tu, err := %s\n\tif u == nil {\n\t\tvar r0 %s\n\t\treturn r0, err\n\t}\n\treturn *u, err\n", call, m.Results[0].Type)
+	}
+
+	b.WriteString("}\n")
+}
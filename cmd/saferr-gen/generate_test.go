@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGenerate_Golden(t *testing.T) {
+
+	filename := filepath.Join("example", "calculator.go")
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, err := generate(filename, src, []string{"Calculator"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want, err := os.ReadFile(filepath.Join("example", "calculator_saferr.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(code, want) {
+		t.Fatalf("example/calculator_saferr.go is out of date; run go generate ./example")
+	}
+}
+
+func TestGenerate_Unsupported(t *testing.T) {
+
+	tests := []string{
+		`package p
+		type S interface { NoContext(x int) (int, error) }`,
+		`package p
+		import "context"
+		type S interface { NoError(ctx context.Context, x int) int }`,
+		`package p
+		import "io"
+		type S interface { io.Reader }`,
+		`package p
+		type S interface {}`,
+	}
+
+	for i, src := range tests {
+		if _, err := generate("p.go", []byte(src), []string{"S"}); !errors.Is(err, errUnsupported) {
+			t.Fatalf("%d: expected errUnsupported, got %v", i, err)
+		}
+	}
+
+	if _, err := generate("p.go", []byte("package p\n"), []string{"S"}); err == nil {
+		t.Fatalf("expected error for missing interface")
+	}
+}
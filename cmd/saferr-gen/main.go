@@ -0,0 +1,74 @@
+// Command saferr-gen generates typed clients and servers for the methods of Go interfaces,
+// so that a service definition stays in sync between its callers and its Responder.
+//
+// For each interface it generates:
+//   - the Keys of the routes, as "Interface.Method"
+//   - request and response envelope types, for methods with several parameters or results
+//   - a Register function that adds a route for each method of an implementation to a mux.TypedHandler
+//   - a Client type that implements the interface by sending Requests using a Requestor
+//
+// Each method must have a context.Context as its first parameter and an error as its last result.
+// Typical usage is with go generate:
+//
+//	//go:generate go run github.com/gford1000-go/saferr/cmd/saferr-gen -type Calculator
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	typeNames := flag.String("type", "", "comma-separated list of interface names; required")
+	output := flag.String("output", "", "output file name; default <dir>/<type>_saferr.go")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: saferr-gen -type T [-output file] [file.go]\n")
+		fmt.Fprintf(os.Stderr, "If no file is given, $GOFILE is used, as set by go generate\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if err := run(*typeNames, *output, flag.Args()); err != nil {
+		fmt.Fprintf(os.Stderr, "saferr-gen: %v\n", err)
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+func run(typeNames, output string, args []string) error {
+	if typeNames == "" {
+		return fmt.Errorf("-type is required")
+	}
+	names := strings.Split(typeNames, ",")
+
+	var filename string
+	switch len(args) {
+	case 0:
+		filename = os.Getenv("GOFILE")
+		if filename == "" {
+			return fmt.Errorf("no file given, and $GOFILE is not set")
+		}
+	case 1:
+		filename = args[0]
+	default:
+		return fmt.Errorf("only one file may be given")
+	}
+
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	code, err := generate(filename, src, names)
+	if err != nil {
+		return err
+	}
+
+	if output == "" {
+		output = filepath.Join(filepath.Dir(filename), strings.ToLower(names[0])+"_saferr.go")
+	}
+	return os.WriteFile(output, code, 0o644)
+}
@@ -0,0 +1,27 @@
+package saferr
+
+import "encoding/json"
+
+// Codec converts requests or responses of type *T to and from bytes, for example so that they
+// can be written to a write-ahead log
+type Codec[T any] interface {
+	Marshal(t *T) ([]byte, error)
+	Unmarshal(data []byte) (*T, error)
+}
+
+// JSONCodec is a Codec using encoding/json
+type JSONCodec[T any] struct{}
+
+// Marshal returns the JSON encoding of t
+func (JSONCodec[T]) Marshal(t *T) ([]byte, error) {
+	return json.Marshal(t)
+}
+
+// Unmarshal returns the *T decoded from the JSON in data
+func (JSONCodec[T]) Unmarshal(data []byte) (*T, error) {
+	var t T
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
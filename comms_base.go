@@ -8,10 +8,14 @@ import (
 
 type commsBase[T any, U any] struct {
 	ch      chan *req[T, U]
+	lanes   *lanes[T, U]
+	fair    *fairQueue[T, U]
+	sched   scheduler[T, U]
 	done    chan struct{}
 	closed  atomic.Bool
 	ctx     context.Context
 	timeout time.Duration
+	clock   Clock
 }
 
 func (c *commsBase[T, U]) isClosed() bool {
@@ -21,3 +25,20 @@ func (c *commsBase[T, U]) isClosed() bool {
 func (c *commsBase[T, U]) setClosed() {
 	c.closed.Store(true)
 }
+
+// scheduler chooses the next request for the Responder, when requests are not taken from a single chan
+type scheduler[T any, U any] interface {
+	// next returns the next request to be handled without blocking, or nil if there are none
+	next() *req[T, U]
+	// ready signals that a request may have been added.  The Responder calls next before
+	// waiting, so a signal that is already pending need not be repeated
+	ready() <-chan struct{}
+}
+
+// notify sends a signal without blocking, since a signal that is already pending suffices
+func notify(signal chan struct{}) {
+	select {
+	case signal <- struct{}{}:
+	default:
+	}
+}
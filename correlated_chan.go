@@ -2,7 +2,6 @@ package saferr
 
 import (
 	"sync"
-	"time"
 )
 
 // correlatedChan supports a non-blocking sender and a blocking receiver pattern.
@@ -11,17 +10,14 @@ import (
 // correlated to the expected id of this instance, which is reset on return to the pool.
 // This will mean that ghost resp[U] are dropped, which is valid as the correlatedChan instance has been
 // recycled through the pool which implies the receiver has gone anyway.
+//
+// Each request receives exactly one resp[U], so a buffer of one allows the sender to deliver without
+// blocking and without a forwarding goroutine, meaning a correlatedChan is simply garbage collected
+// if the pool drops it.
 type correlatedChan[U any] struct {
-	recCh chan *resp[U]
-	dstCh chan *resp[U]
-	id    uint64
-	lck   sync.Mutex
-}
-
-func (c *correlatedChan[U]) getId() uint64 {
-	c.lck.Lock()
-	defer c.lck.Unlock()
-	return c.id
+	ch  chan *resp[U]
+	id  uint64
+	lck sync.Mutex
 }
 
 func (c *correlatedChan[U]) setId(id uint64) {
@@ -30,56 +26,48 @@ func (c *correlatedChan[U]) setId(id uint64) {
 	c.id = id
 }
 
+// send delivers the resp[U] if it is correlated to the current id, otherwise it is discarded.
+// This never blocks the sender.
 func (c *correlatedChan[U]) send(r *resp[U]) {
-	c.recCh <- r // This should be minimally blocking for senders
+	c.lck.Lock()
+	defer c.lck.Unlock()
+
+	// If id is 0, then this instance is back in the pool, so drop the resp[U]
+	// Otherwise, only deliver if the resp.id matches the id of the correlatedChan; everything else is a ghost resp[U]
+	if c.id == 0 || c.id != r.id {
+		r.close()
+		return
+	}
+
+	select {
+	case c.ch <- r:
+	default:
+		// Already holds the resp[U] for this id, so this must be a duplicate
+		r.close()
+	}
 }
 
 func (c *correlatedChan[U]) getReceiverChan() chan *resp[U] {
-	return c.dstCh // Access to blocking chan only for receiveers
+	return c.ch // Access to blocking chan only for receivers
 }
 
-func newCorrelatedChan[U any](maxRetries int, sendTimeout time.Duration, chanSize int) *correlatedChan[U] {
-	c := &correlatedChan[U]{
-		recCh: make(chan *resp[U], chanSize), // Non-blocking
-		dstCh: make(chan *resp[U]),           // BLOCKING
+// reset clears the id and discards any undelivered resp[U], ready for reuse
+func (c *correlatedChan[U]) reset() {
+	c.lck.Lock()
+	defer c.lck.Unlock()
+
+	c.id = 0
+	select {
+	case r := <-c.ch:
+		r.close()
+	default:
 	}
+}
 
-	go func() {
-
-		forwardedOK := func(r *resp[U], d time.Duration) bool {
-			timer := acquireTimer(d)
-			defer releaseTimer(timer)
-
-			select {
-			case c.dstCh <- r:
-				return true
-			case <-timer.C:
-				return false
-			}
-		}
-
-		for {
-			r := <-c.recCh
-
-			// Processing behaviour of the resp[U] depends on id value of the correlatedChan
-			// If 0, then this instancex is back in the pool, so drop the resp[U]
-			// Otherwise, only forward if the resp.id matches the id of the correlatedChan; everything else is a ghost resp[U]
-			id := c.getId()
-			if id == 0 || id != r.id {
-				continue
-			}
-
-			// Expected id, so attempt to forward to Requestor
-			// Timeout and retries ensure that we stop attempting to send if the Requestor has disappeared
-			retries := 0
-			for !forwardedOK(r, sendTimeout) && retries < maxRetries {
-				retries++
-			}
-		}
-
-	}()
-
-	return c
+func newCorrelatedChan[U any]() *correlatedChan[U] {
+	return &correlatedChan[U]{
+		ch: make(chan *resp[U], 1),
+	}
 }
 
 type correlatedChanPool[U any] struct {
@@ -87,11 +75,11 @@ type correlatedChanPool[U any] struct {
 	Put func(c *correlatedChan[U])
 }
 
-func newCorrelatedChanPool[U any](maxRetries int, sendTimeout time.Duration, chanSize int) *correlatedChanPool[U] {
+func newCorrelatedChanPool[U any]() *correlatedChanPool[U] {
 
 	p := sync.Pool{
 		New: func() any {
-			return newCorrelatedChan[U](maxRetries, sendTimeout, chanSize)
+			return newCorrelatedChan[U]()
 		},
 	}
 
@@ -102,7 +90,7 @@ func newCorrelatedChanPool[U any](maxRetries int, sendTimeout time.Duration, cha
 	}
 
 	putter := func(c *correlatedChan[U]) {
-		c.setId(0)
+		c.reset()
 		p.Put(c)
 	}
 
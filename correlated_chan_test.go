@@ -1,6 +1,8 @@
 package saferr
 
 import (
+	"context"
+	"runtime"
 	"sync"
 	"testing"
 	"time"
@@ -8,7 +10,7 @@ import (
 
 func TestCorrelatedChan(t *testing.T) {
 
-	p := newCorrelatedChanPool[int](5, 100*time.Millisecond, 10)
+	p := newCorrelatedChanPool[int]()
 
 	// Basic test of processing: can a resp, sent with the correct id, reach the receiver
 	var id uint64 = 42
@@ -30,8 +32,6 @@ func TestCorrelatedChan(t *testing.T) {
 
 	wg.Wait()
 
-	<-time.After(200 * time.Millisecond) // Allow the goroutine in the correlatedChanPool to run
-
 	select {
 	case r := <-c.getReceiverChan():
 		if r.id != id {
@@ -47,7 +47,7 @@ func TestCorrelatedChan(t *testing.T) {
 
 func TestCorrelatedChan_1(t *testing.T) {
 
-	p := newCorrelatedChanPool[int](5, 100*time.Millisecond, 10)
+	p := newCorrelatedChanPool[int]()
 
 	// Tests for ghost values being discarded
 	var id uint64 = 99
@@ -71,8 +71,6 @@ func TestCorrelatedChan_1(t *testing.T) {
 
 	wg.Wait()
 
-	<-time.After(200 * time.Millisecond) // Allow the goroutine in the correlatedChanPool to run
-
 	select {
 	case r := <-c.getReceiverChan():
 		if r.id != id {
@@ -85,3 +83,67 @@ func TestCorrelatedChan_1(t *testing.T) {
 		t.Fatal("should have received resp")
 	}
 }
+
+func TestCorrelatedChan_2(t *testing.T) {
+
+	p := newCorrelatedChanPool[int]()
+
+	// An undelivered resp must not be received after the correlatedChan is reused
+	c := p.Get(1)
+
+	first, second := 1, 2
+	c.send(&resp[int]{id: 1, data: &first})
+
+	p.Put(c)
+
+	// Sending after return to the pool is discarded
+	c.send(&resp[int]{id: 1, data: &first})
+
+	c.setId(2)
+	c.send(&resp[int]{id: 2, data: &second})
+
+	r := <-c.getReceiverChan()
+	if *r.data != second {
+		t.Fatalf("expected resp for the new id, got: %d", *r.data)
+	}
+}
+
+func TestCorrelatedChan_NoGoroutineLeak(t *testing.T) {
+
+	baseline := runtime.NumGoroutine()
+
+	reflect := func(ctx context.Context, input *int) (*int, error) {
+		return input, nil
+	}
+
+	// Many short-lived Go services, each with concurrent Requestors, to create many pooled correlatedChans
+	for range 20 {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		requestor := Go(ctx, reflect)
+
+		var wg sync.WaitGroup
+		for i := range 50 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if response, err := requestor.Send(ctx, &i); err != nil || *response != i {
+					t.Errorf("unexpected response: %v, %v", response, err)
+				}
+			}()
+		}
+		wg.Wait()
+
+		cancel()
+	}
+
+	// Allow the Responder goroutines to observe their cancelled contexts
+	deadline := time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > baseline && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if n := runtime.NumGoroutine(); n > baseline {
+		t.Fatalf("goroutines did not return to baseline: expected %d, got %d", baseline, n)
+	}
+}
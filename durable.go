@@ -0,0 +1,149 @@
+package saferr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gford1000-go/saferr/types"
+	"github.com/gford1000-go/saferr/wal"
+)
+
+// ErrDurableLog is returned by Send if the request cannot be encoded or appended to the write-ahead log
+var ErrDurableLog = &Error{Code: CodeUnavailable, Message: "unable to log request"}
+
+// Durable configures GoDurable
+type Durable[T any] struct {
+	// Log is the write-ahead log to which requests are appended.  It is not closed by GoDurable
+	Log *wal.Log
+	// Codec encodes requests for the Log.  Defaults to JSONCodec
+	Codec Codec[T]
+	// OnReplay, if set, is called for each request replayed from the Log, with the error returned by
+	// the handler, or by the Codec if the request could not be decoded (in which case t is nil)
+	OnReplay func(ctx context.Context, t *T, err error)
+}
+
+type durableReq[T any] struct {
+	id uint64
+	t  *T
+}
+
+type durableRequestor[T, U any] struct {
+	requestor types.Requestor[durableReq[T], U]
+	log       *wal.Log
+	codec     Codec[T]
+}
+
+// Send appends the request to the Log before sending it, so that it is replayed if it is not handled
+func (r *durableRequestor[T, U]) Send(ctx context.Context, t *T) (*U, error) {
+	// A request that cannot be sent is not logged, so that it is never replayed
+	if ctx.Err() != nil {
+		return nil, ErrContextCompleted
+	}
+
+	data, err := r.codec.Marshal(t)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDurableLog, err)
+	}
+
+	id, err := r.log.Append(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDurableLog, err)
+	}
+
+	u, err := r.requestor.Send(ctx, &durableReq[T]{id: id, t: t})
+
+	// The request was never queued, so it must not be replayed
+	if neverQueued(err) {
+		r.log.Complete(id)
+	}
+
+	return u, err
+}
+
+// GoDurable behaves as Go, except that each request is appended to the write-ahead Log of d before it is sent,
+// and marked complete once handler returns.  Requests that were logged but not handled, for example because the
+// process stopped, are replayed to handler when GoDurable is next called with the same Log, before any new requests.
+//
+// Delivery is at-least-once: a request may be handled again if the process stops after handler returns but before
+// it is marked complete, and a request whose Send times out may still be handled, either now or when replayed.
+func GoDurable[T, U any](ctx context.Context, handler func(context.Context, *T) (*U, error), d *Durable[T], opts ...func(*Options)) (types.Requestor[T, U], error) {
+	if d == nil || d.Log == nil {
+		return nil, fmt.Errorf("%w: no log", ErrDurableLog)
+	}
+
+	codec := d.Codec
+	if codec == nil {
+		codec = JSONCodec[T]{}
+	}
+
+	o := defaults
+	for _, opt := range opts {
+		opt(&o)
+	}
+	preStart := o.GoPreStart
+
+	log := d.Log
+	pending := log.Pending()
+
+	complete := func(id uint64, err error) error {
+		if cerr := log.Complete(id); cerr != nil {
+			return errors.Join(err, fmt.Errorf("%w: %w", ErrDurableLog, cerr))
+		}
+		return err
+	}
+
+	durableHandler := func(ctx context.Context, r *durableReq[T]) (*U, error) {
+		// A panic is reported to the Requestor as an error, so the request is still complete
+		returned := false
+		defer func() {
+			if !returned {
+				log.Complete(r.id)
+			}
+		}()
+
+		u, err := handler(ctx, r.t)
+		returned = true
+		return u, complete(r.id, err)
+	}
+
+	replay := func(ctx context.Context, rec wal.Record) {
+		var t *T
+		err := func() (err error) {
+			defer func() {
+				if rc := recover(); rc != nil {
+					err = fmt.Errorf("%w: %v", ErrUncaughtHandlerPanic, rc)
+				}
+			}()
+			if t, err = codec.Unmarshal(rec.Data); err != nil {
+				return err
+			}
+			_, err = handler(ctx, t)
+			return err
+		}()
+		err = complete(rec.ID, err)
+		if d.OnReplay != nil {
+			d.OnReplay(ctx, t, err)
+		}
+	}
+
+	opts = append(opts[:len(opts):len(opts)],
+		WithGoPreStart(func(ctx context.Context) (context.Context, error) {
+			var err error
+			if preStart != nil {
+				if ctx, err = preStart(ctx); err != nil {
+					return ctx, err
+				}
+			}
+			for _, rec := range pending {
+				replay(ctx, rec)
+			}
+			return ctx, nil
+		}))
+
+	return &durableRequestor[T, U]{
+		requestor: Go(ctx, durableHandler, opts...),
+		log:       log,
+		codec:     codec,
+	}, nil
+}
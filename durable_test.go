@@ -0,0 +1,179 @@
+package saferr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gford1000-go/saferr/wal"
+)
+
+type ingestEvent struct {
+	Source string
+	Value  int
+}
+
+func ExampleGoDurable() {
+
+	dir, _ := os.MkdirTemp("", "saferr-wal")
+	defer os.RemoveAll(dir)
+
+	log, _ := wal.Open(dir)
+	defer log.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := func(ctx context.Context, e *ingestEvent) (*bool, error) {
+		fmt.Println("stored", e.Source, e.Value)
+		ok := true
+		return &ok, nil
+	}
+
+	requestor, err := GoDurable(ctx, store, &Durable[ingestEvent]{Log: log})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	requestor.Send(ctx, &ingestEvent{Source: "sensor-1", Value: 42})
+
+	fmt.Println("pending:", len(log.Pending()))
+
+	// Output:
+	// stored sensor-1 42
+	// pending: 0
+}
+
+func TestGoDurable_Replay(t *testing.T) {
+
+	dir := t.TempDir()
+
+	// First run: the handler blocks, so that requests are queued but not handled when the process "stops"
+	log, err := wal.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	block := make(chan struct{})
+	ended := make(chan error, 1)
+
+	var handled []int
+	var lck sync.Mutex
+
+	blocked := func(ctx context.Context, e *ingestEvent) (*int, error) {
+		<-block
+		return nil, errors.New("stopped")
+	}
+
+	requestor, err := GoDurable(ctx, blocked, &Durable[ingestEvent]{Log: log},
+		WithRequestorTimeout(50*time.Millisecond),
+		WithGoPostEnd(func(err error) { ended <- err }))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 1; i <= 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			requestor.Send(ctx, &ingestEvent{Source: "s", Value: i})
+		}()
+	}
+	wg.Wait()
+
+	// Simulate the process stopping: the in-progress request is abandoned, not completed
+	cancel()
+	log.Close()
+	close(block)
+	<-ended
+
+	// Second run: the requests are replayed before new requests are handled
+	log, err = wal.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer log.Close()
+
+	if n := len(log.Pending()); n != 3 {
+		t.Fatalf("expected 3 requests to be recovered, got %d", n)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+
+	var replayed int
+	record := func(ctx context.Context, e *ingestEvent) (*int, error) {
+		lck.Lock()
+		defer lck.Unlock()
+		handled = append(handled, e.Value)
+		return &e.Value, nil
+	}
+
+	requestor, err = GoDurable(ctx, record, &Durable[ingestEvent]{
+		Log:      log,
+		OnReplay: func(ctx context.Context, e *ingestEvent, err error) { replayed++ },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v, err := requestor.Send(ctx, &ingestEvent{Source: "s", Value: 4}); err != nil || *v != 4 {
+		t.Fatalf("unexpected response: %v, %v", v, err)
+	}
+
+	lck.Lock()
+	defer lck.Unlock()
+
+	if len(handled) != 4 || handled[3] != 4 || replayed != 3 {
+		t.Fatalf("expected 3 replayed requests then the new request, got %v (%d replayed)", handled, replayed)
+	}
+	if n := len(log.Pending()); n != 0 {
+		t.Fatalf("expected no pending requests, got %d", n)
+	}
+}
+
+func TestGoDurable_NotQueued(t *testing.T) {
+
+	dir := t.TempDir()
+
+	log, err := wal.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	handler := func(ctx context.Context, e *ingestEvent) (*int, error) { return &e.Value, nil }
+
+	requestor, err := GoDurable(ctx, handler, &Durable[ingestEvent]{Log: log})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A caller whose context has completed is told the request failed, so it must not be replayed
+	callerCtx, callerCancel := context.WithCancel(ctx)
+	callerCancel()
+	if _, err := requestor.Send(callerCtx, &ingestEvent{Source: "s", Value: 1}); !errors.Is(err, ErrContextCompleted) {
+		t.Fatalf("expected ErrContextCompleted, got %v", err)
+	}
+	log.Close()
+
+	log, err = wal.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer log.Close()
+
+	if n := len(log.Pending()); n != 0 {
+		t.Fatalf("expected no pending requests, got %d", n)
+	}
+}
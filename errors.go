@@ -1,30 +1,72 @@
 package saferr
 
-import "errors"
+import "github.com/gford1000-go/saferr/types"
+
+// Error is the structured error returned by this package, carrying a Code, retryability, details and a cause.
+// See types.Error for details.
+type Error = types.Error
+
+// Code classifies an Error.  See types.Code for the available values.
+type Code = types.Code
+
+// The available Codes, re-exported from types for convenience
+const (
+	CodeUnknown            = types.CodeUnknown
+	CodeInvalidArgument    = types.CodeInvalidArgument
+	CodeNotFound           = types.CodeNotFound
+	CodeAlreadyExists      = types.CodeAlreadyExists
+	CodeFailedPrecondition = types.CodeFailedPrecondition
+	CodePermissionDenied   = types.CodePermissionDenied
+	CodeResourceExhausted  = types.CodeResourceExhausted
+	CodeDeadlineExceeded   = types.CodeDeadlineExceeded
+	CodeCanceled           = types.CodeCanceled
+	CodeUnavailable        = types.CodeUnavailable
+	CodeUnimplemented      = types.CodeUnimplemented
+	CodeInternal           = types.CodeInternal
+)
 
 // ErrSendTimeout returned if the Requestor.Send times out
-var ErrSendTimeout = errors.New("request timedout exceeded")
+var ErrSendTimeout = &Error{Code: CodeDeadlineExceeded, Message: "request timedout exceeded", Retryable: true}
 
 // ErrRequestorIsClosed returned if the Requestor is closed but a transmission is attempted
-var ErrRequestorIsClosed = errors.New("requestor closed")
+var ErrRequestorIsClosed = &Error{Code: CodeUnavailable, Message: "requestor closed"}
 
 // ErrResponderIsClosed returned if the Responder has closed
-var ErrResponderIsClosed = errors.New("receiver closed")
+var ErrResponderIsClosed = &Error{Code: CodeUnavailable, Message: "receiver closed"}
 
 // ErrCommsChannelIsClosed returned when it is known that transmission is not possible
-var ErrCommsChannelIsClosed = errors.New("comms channel has been closed")
+var ErrCommsChannelIsClosed = &Error{Code: CodeUnavailable, Message: "comms channel has been closed"}
 
 // ErrContextCompleted returned if the request is being attempted but the context has completed
-var ErrContextCompleted = errors.New("context is completed")
+var ErrContextCompleted = &Error{Code: CodeCanceled, Message: "context is completed"}
+
+// ErrInvalidPriority returned if a request is sent with a priority for which there is no lane
+var ErrInvalidPriority = &Error{Code: CodeInvalidArgument, Message: "invalid priority"}
 
 // ErrUncaughtHandlerPanic returned if a panic occurs when handling a request
-var ErrUncaughtHandlerPanic = errors.New("recovered receiver panic during handling")
+var ErrUncaughtHandlerPanic = types.ErrHandlerPanic
 
 // ErrUncaughtSendPanic returned if a send attempt generates a panic
-var ErrUncaughtSendPanic = errors.New("recovered panic during send")
+var ErrUncaughtSendPanic = &Error{Code: CodeInternal, Message: "recovered panic during send"}
 
 // ErrRequestorGoneAway returned when the Responder decides that its Requestor has gone
-var ErrRequestorGoneAway = errors.New("requestor gone away")
+var ErrRequestorGoneAway = &Error{Code: CodeUnavailable, Message: "requestor gone away"}
 
 // ErrUnableToSendRequest returned when a request cannot be sent after multiple attempts
-var ErrUnableToSendRequest = errors.New("unable to send request")
+var ErrUnableToSendRequest = &Error{Code: CodeResourceExhausted, Message: "unable to send request", Retryable: true}
+
+// CodeOf returns the Code of err, or CodeUnknown if none can be determined
+func CodeOf(err error) Code {
+	return types.CodeOf(err)
+}
+
+// IsRetryable returns true if err is (or wraps) an Error that is marked as Retryable
+func IsRetryable(err error) bool {
+	return types.IsRetryable(err)
+}
+
+// AsError returns err as an Error, preserving the Code of any Error it wraps, so that it can be
+// inspected or serialised.  Returns nil if err is nil
+func AsError(err error) *Error {
+	return types.AsError(err)
+}
@@ -0,0 +1,127 @@
+package saferr
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/gford1000-go/saferr/mux"
+)
+
+func ExampleError() {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	lookup := func(ctx context.Context, id *int) (*string, error) {
+		return nil, (&Error{
+			Code:    CodeNotFound,
+			Message: "customer not found",
+		}).WithDetail("id", *id)
+	}
+
+	requestor := Go(ctx, lookup)
+
+	id := 42
+	_, err := requestor.Send(ctx, &id)
+
+	var e *Error
+	if errors.As(err, &e) {
+		fmt.Println(e.Code, e.Details["id"], IsRetryable(err))
+	}
+
+	// Output: not_found 42 false
+}
+
+func TestError_SentinelCodes(t *testing.T) {
+
+	tests := []struct {
+		err       error
+		code      Code
+		retryable bool
+	}{
+		{ErrSendTimeout, CodeDeadlineExceeded, true},
+		{ErrRequestorGoneAway, CodeUnavailable, false},
+		{ErrContextCompleted, CodeCanceled, false},
+		{ErrUnableToSendRequest, CodeResourceExhausted, true},
+		{fmt.Errorf("%w: %v", ErrUncaughtHandlerPanic, "boom"), CodeInternal, false},
+		{mux.ErrHandlerNotFound, CodeNotFound, false},
+		{context.DeadlineExceeded, CodeDeadlineExceeded, true},
+		{errors.New("plain"), CodeUnknown, false},
+	}
+
+	for i, test := range tests {
+		if c := CodeOf(test.err); c != test.code {
+			t.Fatalf("%d: expected code %v, got %v", i, test.code, c)
+		}
+		if r := IsRetryable(test.err); r != test.retryable {
+			t.Fatalf("%d: expected retryable %v, got %v", i, test.retryable, r)
+		}
+	}
+}
+
+func TestError_JSONRoundTrip(t *testing.T) {
+
+	wrapped := fmt.Errorf("%w: %v", ErrUncaughtHandlerPanic, "boom")
+
+	b, err := json.Marshal(AsError(wrapped))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var restored Error
+	if err := json.Unmarshal(b, &restored); err != nil {
+		t.Fatal(err)
+	}
+
+	if restored.Code != CodeInternal {
+		t.Fatalf("expected code %v, got %v", CodeInternal, restored.Code)
+	}
+	if !errors.Is(&restored, ErrUncaughtHandlerPanic) {
+		t.Fatalf("restored error should match sentinel: %v", &restored)
+	}
+	if restored.Error() != wrapped.Error() {
+		t.Fatalf("expected message %q, got %q", wrapped.Error(), restored.Error())
+	}
+	if errors.Is(&restored, ErrUncaughtSendPanic) {
+		t.Fatal("restored error should not match a different sentinel")
+	}
+}
+
+func TestError_GobRoundTrip(t *testing.T) {
+
+	sent := ErrSendTimeout.WithDetail("attempt", 2).WithCause(ErrResponderIsClosed)
+
+	var b bytes.Buffer
+	if err := gob.NewEncoder(&b).Encode(sent); err != nil {
+		t.Fatal(err)
+	}
+
+	var restored Error
+	if err := gob.NewDecoder(&b).Decode(&restored); err != nil {
+		t.Fatal(err)
+	}
+
+	if !errors.Is(&restored, ErrSendTimeout) || !errors.Is(&restored, ErrResponderIsClosed) {
+		t.Fatalf("restored error should match both sentinels: %v", &restored)
+	}
+	if !restored.Retryable || restored.Details["attempt"] != float64(2) {
+		t.Fatalf("unexpected restored error: %+v", restored)
+	}
+}
+
+func TestError_WithDetailDoesNotModifySentinel(t *testing.T) {
+
+	e := ErrSendTimeout.WithDetail("attempt", 3)
+
+	if ErrSendTimeout.Details != nil {
+		t.Fatal("sentinel should not be modified")
+	}
+	if !errors.Is(e, ErrSendTimeout) {
+		t.Fatal("copy should still match the sentinel")
+	}
+}
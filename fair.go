@@ -0,0 +1,228 @@
+package saferr
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/gford1000-go/saferr/types"
+)
+
+// ErrTenantQueueFull returned if a request is sent when its tenant already has the maximum number of requests waiting
+var ErrTenantQueueFull = &Error{Code: CodeResourceExhausted, Message: "tenant queue full", Retryable: true}
+
+// ErrTenantKey is wrapped in the panic of New if the request type of the WithFairQueueing key function does not match the Requestor
+var ErrTenantKey = &Error{Code: CodeInvalidArgument, Message: "tenant key has the wrong request type"}
+
+// TenantStats describes the requests of a tenant when fair queueing is enabled
+type TenantStats struct {
+	// Queued is the number of requests waiting to be handled
+	Queued int
+	// Dequeued is the number of requests taken to be handled
+	Dequeued uint64
+	// Rejected is the number of requests rejected because the queue of the tenant, or the total
+	// of all the queues, was full
+	Rejected uint64
+}
+
+type tenantKey struct{}
+
+// WithTenant returns a context that identifies the tenant (or caller) making requests, for use with fair queueing
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant set by WithTenant, or an empty string if there is none
+func TenantFromContext(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return tenant
+}
+
+type tenantRequestor[T any, U any] struct {
+	requestor types.Requestor[T, U]
+	tenant    string
+}
+
+func (r *tenantRequestor[T, U]) Send(ctx context.Context, t *T) (*U, error) {
+	return r.requestor.Send(WithTenant(ctx, r.tenant), t)
+}
+
+// ForTenant returns a Requestor that sends requests as the tenant, so that each caller can be given
+// its own Requestor and so its own queue
+func ForTenant[T any, U any](r types.Requestor[T, U], tenant string) types.Requestor[T, U] {
+	return &tenantRequestor[T, U]{requestor: r, tenant: tenant}
+}
+
+// tenantQueue holds the requests waiting for a tenant
+type tenantQueue[T any, U any] struct {
+	name    string
+	reqs    []*req[T, U]
+	deficit int
+	rec     *tenantRecord
+}
+
+// tenantRecord holds the stats of a tenant, which are kept whilst it is idle
+type tenantRecord struct {
+	stats TenantStats
+	// idle is the element of the tenant in fairQueue.idle whilst it has no requests waiting
+	idle *list.Element
+}
+
+// fairQueue holds a queue for each tenant, and takes requests from them by deficit round robin,
+// so that each tenant with requests waiting receives its weight of requests in turn
+type fairQueue[T any, U any] struct {
+	lck sync.Mutex
+	// tenants holds the queue of each tenant with requests waiting, which is removed once it is empty
+	tenants map[string]*tenantQueue[T, U]
+	// records holds the record of each tenant with requests waiting, and of up to idleLimit idle tenants
+	records   map[string]*tenantRecord
+	idle      *list.List // names of the idle tenants, the longest idle first
+	idleLimit int
+	// active holds the tenants with requests waiting, in round robin sequence
+	active []*tenantQueue[T, U]
+	size   int
+	// queued is the number of requests waiting across all the tenants, which may not exceed limit
+	queued int
+	limit  int
+	weight func(tenant string) int
+	signal chan struct{}
+}
+
+func newFairQueue[T any, U any](size, limit, idleLimit int, weight func(tenant string) int) *fairQueue[T, U] {
+	return &fairQueue[T, U]{
+		tenants:   map[string]*tenantQueue[T, U]{},
+		records:   map[string]*tenantRecord{},
+		idle:      list.New(),
+		idleLimit: idleLimit,
+		size:      size,
+		limit:     limit,
+		weight:    weight,
+		signal:    make(chan struct{}, 1),
+	}
+}
+
+// push adds the request to the queue of the tenant, unless it, or the total of all the queues, is full
+func (f *fairQueue[T, U]) push(tenant string, req *req[T, U]) error {
+	f.lck.Lock()
+	defer f.lck.Unlock()
+
+	tq, ok := f.tenants[tenant]
+	if ok && len(tq.reqs) >= f.size {
+		tq.rec.stats.Rejected++
+		return fmt.Errorf("%w: tenant %q", ErrTenantQueueFull, tenant)
+	}
+	if f.queued >= f.limit {
+		f.record(tenant).stats.Rejected++
+		return fmt.Errorf("%w: %d requests waiting for all tenants", ErrUnableToSendRequest, f.queued)
+	}
+
+	if !ok {
+		rec := f.record(tenant)
+		f.idle.Remove(rec.idle)
+		rec.idle = nil
+		tq = &tenantQueue[T, U]{name: tenant, rec: rec}
+		f.tenants[tenant] = tq
+		f.active = append(f.active, tq)
+	}
+	tq.reqs = append(tq.reqs, req)
+	f.queued++
+	notify(f.signal)
+	return nil
+}
+
+// record returns the record of the tenant, which is added as idle if the tenant is new
+func (f *fairQueue[T, U]) record(tenant string) *tenantRecord {
+	rec, ok := f.records[tenant]
+	if !ok {
+		rec = &tenantRecord{}
+		f.records[tenant] = rec
+		f.setIdle(tenant, rec)
+	}
+	return rec
+}
+
+// setIdle adds the tenant to the idle tenants, discarding the records of those idle the longest
+// beyond idleLimit
+func (f *fairQueue[T, U]) setIdle(tenant string, rec *tenantRecord) {
+	rec.idle = f.idle.PushBack(tenant)
+	for f.idle.Len() > f.idleLimit {
+		delete(f.records, f.idle.Remove(f.idle.Front()).(string))
+	}
+}
+
+// next takes the next request to be handled without blocking, returning nil if all queues are empty
+func (f *fairQueue[T, U]) next() *req[T, U] {
+	f.lck.Lock()
+	defer f.lck.Unlock()
+
+	if len(f.active) == 0 {
+		return nil
+	}
+
+	// The tenant at the front starts a new turn once its deficit is spent
+	tq := f.active[0]
+	if tq.deficit < 1 {
+		tq.deficit += f.weightOf(tq.name)
+	}
+
+	req := tq.reqs[0]
+	tq.reqs[0] = nil
+	tq.reqs = tq.reqs[1:]
+	tq.deficit--
+	tq.rec.stats.Dequeued++
+	f.queued--
+
+	switch {
+	case len(tq.reqs) == 0:
+		// An idle tenant does not accumulate credit, and its queue is removed
+		delete(f.tenants, tq.name)
+		f.setIdle(tq.name, tq.rec)
+		f.active[0] = nil
+		f.active = f.active[1:]
+	case tq.deficit < 1:
+		f.active = append(f.active[1:], tq)
+	}
+	return req
+}
+
+// ready signals that a request may have been added to a queue
+func (f *fairQueue[T, U]) ready() <-chan struct{} {
+	return f.signal
+}
+
+func (f *fairQueue[T, U]) weightOf(tenant string) int {
+	if f.weight != nil {
+		if w := f.weight(tenant); w > 0 {
+			return w
+		}
+	}
+	return 1
+}
+
+// stats returns the TenantStats of each tenant that has a record
+func (f *fairQueue[T, U]) stats() map[string]TenantStats {
+	f.lck.Lock()
+	defer f.lck.Unlock()
+
+	m := make(map[string]TenantStats, len(f.records))
+	for name, rec := range f.records {
+		m[name] = rec.stats
+	}
+	for name, tq := range f.tenants {
+		s := m[name]
+		s.Queued = len(tq.reqs)
+		m[name] = s
+	}
+	return m
+}
+
+// FairQueueStats returns the TenantStats of each tenant that has sent a request to the Requestor, which
+// must have been created by New or Go with WithFairQueueing.  Only the TenantStatsLimit tenants most
+// recently active are reported amongst those with no requests waiting.  Returns nil for any other Requestor
+func FairQueueStats[T any, U any](r types.Requestor[T, U]) map[string]TenantStats {
+	if rr, ok := r.(*requestor[T, U]); ok && rr.fair != nil {
+		return rr.fair.stats()
+	}
+	return nil
+}
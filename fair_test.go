@@ -0,0 +1,275 @@
+package saferr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gford1000-go/saferr/types"
+)
+
+// enqueue sends each request as the tenant in its own goroutine, waiting until it is queued before
+// sending the next, so that the sequence of each tenant's requests is known
+func enqueue(t *testing.T, r types.Requestor[string, string], wg *sync.WaitGroup, tenant string, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		before := FairQueueStats(r)[tenant].Queued
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := r.Send(WithTenant(context.Background(), tenant), &name); err != nil {
+				t.Error(err)
+			}
+		}()
+		deadline := time.Now().Add(time.Second)
+		for FairQueueStats(r)[tenant].Queued == before {
+			if time.Now().After(deadline) {
+				t.Fatalf("request %s not queued", name)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+}
+
+func ExampleWithFairQueueing() {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	handler := func(ctx context.Context, s *string) (*string, error) {
+		r := "handled " + *s
+		return &r, nil
+	}
+
+	requestor := Go(ctx, handler, WithFairQueueing[string](nil), WithTenantQueueSize(10))
+
+	// Each caller has its own queue
+	acme := ForTenant(requestor, "acme")
+	globex := ForTenant(requestor, "globex")
+
+	order := "order"
+	r, _ := acme.Send(ctx, &order)
+	fmt.Println(*r)
+	r, _ = globex.Send(ctx, &order)
+	fmt.Println(*r)
+
+	stats := FairQueueStats(requestor)
+	fmt.Println(stats["acme"].Dequeued, stats["globex"].Dequeued)
+
+	// Output:
+	// handled order
+	// handled order
+	// 1 1
+}
+
+func TestFairQueueing_RoundRobin(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	weights := map[string]int{"a": 2}
+	rec := newLaneRecorder()
+	requestor := Go(ctx, rec.handle,
+		WithFairQueueing[string](nil),
+		WithTenantWeight(func(tenant string) int { return weights[tenant] }))
+
+	var wg sync.WaitGroup
+	rec.block(requestor, &wg)
+
+	enqueue(t, requestor, &wg, "a", "a1", "a2", "a3", "a4", "a5")
+	enqueue(t, requestor, &wg, "b", "b1", "b2")
+	enqueue(t, requestor, &wg, "c", "c1")
+	close(rec.release)
+	wg.Wait()
+
+	want := []string{"block", "a1", "a2", "b1", "c1", "a3", "a4", "b2", "a5"}
+	if !reflect.DeepEqual(rec.order, want) {
+		t.Fatalf("expected %v, got %v", want, rec.order)
+	}
+}
+
+func TestFairQueueing_QueueFull(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rec := newLaneRecorder()
+	requestor := Go(ctx, rec.handle, WithFairQueueing[string](nil), WithTenantQueueSize(2))
+
+	var wg sync.WaitGroup
+	rec.block(requestor, &wg)
+
+	enqueue(t, requestor, &wg, "noisy", "n1", "n2")
+
+	n3 := "n3"
+	if _, err := requestor.Send(WithTenant(ctx, "noisy"), &n3); !errors.Is(err, ErrTenantQueueFull) || !IsRetryable(err) {
+		t.Fatalf("expected ErrTenantQueueFull, got %v", err)
+	}
+
+	// Other tenants are unaffected
+	enqueue(t, requestor, &wg, "quiet", "q1")
+
+	stats := FairQueueStats(requestor)
+	if want := (TenantStats{Queued: 2, Rejected: 1}); stats["noisy"] != want {
+		t.Fatalf("expected %+v, got %+v", want, stats["noisy"])
+	}
+
+	close(rec.release)
+	wg.Wait()
+
+	stats = FairQueueStats(requestor)
+	if want := (TenantStats{Dequeued: 2, Rejected: 1}); stats["noisy"] != want {
+		t.Fatalf("expected %+v, got %+v", want, stats["noisy"])
+	}
+	if want := (TenantStats{Dequeued: 1}); stats["quiet"] != want {
+		t.Fatalf("expected %+v, got %+v", want, stats["quiet"])
+	}
+}
+
+func TestFairQueueing_MetaKey(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	type meta struct {
+		Tenant string
+	}
+	type request = types.Request[int, meta, string]
+
+	handler := func(ctx context.Context, r *request) (*int, error) {
+		return r.Data, nil
+	}
+
+	requestor := Go(ctx, handler,
+		WithFairQueueing(func(ctx context.Context, r *request) string { return r.Meta.Tenant }),
+		WithWorkers(4), WithChanSize(200))
+
+	var wg sync.WaitGroup
+	for i := range 200 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			u, err := requestor.Send(ctx, &request{Meta: meta{Tenant: fmt.Sprint(i % 5)}, Data: &i})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if *u != i {
+				t.Errorf("expected %d, got %d", i, *u)
+			}
+		}()
+	}
+	wg.Wait()
+
+	stats := FairQueueStats(requestor)
+	for tenant := range 5 {
+		if s := stats[fmt.Sprint(tenant)]; s.Dequeued != 40 {
+			t.Fatalf("expected 40 requests for tenant %d, got %+v", tenant, s)
+		}
+	}
+}
+
+func TestFairQueueing_WrongKeyType(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	func() {
+		defer func() {
+			if err, _ := recover().(error); !errors.Is(err, ErrTenantKey) {
+				t.Fatalf("expected ErrTenantKey, got %v", err)
+			}
+		}()
+		Go(ctx, func(ctx context.Context, s *string) (*string, error) { return s, nil },
+			WithFairQueueing[int](nil))
+	}()
+	if FairQueueStats(Go(ctx, func(ctx context.Context, s *string) (*string, error) { return s, nil })) != nil {
+		t.Fatal("expected no stats without fair queueing")
+	}
+}
+
+func TestFairQueueing_IdleTenantsRemoved(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	handler := func(ctx context.Context, s *string) (*string, error) { return s, nil }
+	r := Go(ctx, handler, WithFairQueueing[string](nil))
+
+	for i := range 100 {
+		s := fmt.Sprint(i)
+		if _, err := r.Send(WithTenant(ctx, s), &s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f := r.(*requestor[string, string]).fair
+	f.lck.Lock()
+	queues := len(f.tenants)
+	f.lck.Unlock()
+	if queues != 0 {
+		t.Fatalf("expected the queues of idle tenants to be removed, got %d", queues)
+	}
+
+	// Their stats are still reported
+	stats := FairQueueStats(r)
+	if len(stats) != 100 || stats["42"] != (TenantStats{Dequeued: 1}) {
+		t.Fatalf("unexpected stats: %d tenants, %+v", len(stats), stats["42"])
+	}
+}
+
+func TestFairQueueing_StatsLimit(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	handler := func(ctx context.Context, s *string) (*string, error) { return s, nil }
+	r := Go(ctx, handler, WithFairQueueing[string](nil), WithTenantStatsLimit(10))
+
+	for i := range 100 {
+		s := fmt.Sprint(i)
+		if _, err := r.Send(WithTenant(ctx, s), &s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Only the most recently active idle tenants are kept
+	stats := FairQueueStats(r)
+	if len(stats) != 10 {
+		t.Fatalf("expected stats of 10 tenants, got %d", len(stats))
+	}
+	if _, ok := stats["99"]; !ok {
+		t.Fatalf("expected stats of the last tenant, got %v", stats)
+	}
+}
+
+func TestFairQueueing_TotalLimit(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rec := newLaneRecorder()
+	requestor := Go(ctx, rec.handle, WithFairQueueing[string](nil), WithChanSize(3))
+
+	var wg sync.WaitGroup
+	rec.block(requestor, &wg)
+
+	enqueue(t, requestor, &wg, "a", "a1", "a2")
+	enqueue(t, requestor, &wg, "b", "b1")
+
+	// Every tenant is within its own limit, but the total is full
+	c1 := "c1"
+	if _, err := requestor.Send(WithTenant(ctx, "c"), &c1); !errors.Is(err, ErrUnableToSendRequest) {
+		t.Fatalf("expected ErrUnableToSendRequest, got %v", err)
+	}
+	if want := (TenantStats{Rejected: 1}); FairQueueStats(requestor)["c"] != want {
+		t.Fatalf("expected %+v, got %+v", want, FairQueueStats(requestor)["c"])
+	}
+
+	close(rec.release)
+	wg.Wait()
+}
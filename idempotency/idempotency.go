@@ -0,0 +1,205 @@
+// Package idempotency deduplicates requests that carry the same idempotency key, so that a client
+// which retries, for example after saferr.ErrSendTimeout, receives the response of the request that
+// was already handled rather than the handler running again.
+//
+// Results are held in a Store for a TTL.  MemoryStore and FileStore are provided, and other
+// Stores (such as a shared cache) can be used by implementing the Store interface.
+package idempotency
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gford1000-go/saferr"
+	"github.com/gford1000-go/saferr/types"
+)
+
+// ErrStore is returned if the Store cannot be read, in which case the handler is not run, since it
+// cannot be known whether the request has already been handled
+var ErrStore = &types.Error{Code: types.CodeUnavailable, Message: "idempotency store unavailable", Retryable: true}
+
+// Options configure Handler
+type Options struct {
+	// TTL is how long a Result is used for repeated requests.  Defaults to 24 hours
+	TTL time.Duration
+	// PurgeInterval is the minimum time between removals of expired Results from the Store.
+	// Defaults to the TTL; zero or less disables purging
+	PurgeInterval time.Duration
+	// Clock provides the time used for expiry.  Defaults to the wall clock
+	Clock saferr.Clock
+	// OnStoreError, if set, is called with errors from the Store that do not affect the response,
+	// such as a failure to store a Result or to purge expired Results
+	OnStoreError func(key string, err error)
+}
+
+// WithTTL sets how long a Result is used for repeated requests
+func WithTTL(d time.Duration) func(*Options) {
+	return func(o *Options) {
+		if d > 0 {
+			o.TTL = d
+		}
+	}
+}
+
+// WithPurgeInterval sets the minimum time between removals of expired Results from the Store.
+// Zero or less disables purging, for example if the Store expires Results itself
+func WithPurgeInterval(d time.Duration) func(*Options) {
+	return func(o *Options) {
+		o.PurgeInterval = d
+	}
+}
+
+// WithClock sets the Clock used for expiry, allowing a saferr.FakeClock to be used in tests
+func WithClock(c saferr.Clock) func(*Options) {
+	return func(o *Options) {
+		if c != nil {
+			o.Clock = c
+		}
+	}
+}
+
+// WithStoreErrorHandler sets the function called with errors from the Store that do not affect the response
+func WithStoreErrorHandler(f func(key string, err error)) func(*Options) {
+	return func(o *Options) {
+		o.OnStoreError = f
+	}
+}
+
+// MetaKey returns a key function for Handlers of mux Requests, using f to obtain the idempotency key
+// from the Meta of the Request.  The Key of the Request is included, so that the same idempotency key
+// used with different routes identifies different requests
+func MetaKey[T, M any, K comparable](f func(m M) string) func(context.Context, *types.Request[T, M, K]) string {
+	return func(_ context.Context, r *types.Request[T, M, K]) string {
+		k := f(r.Meta)
+		if k == "" {
+			return ""
+		}
+		return fmt.Sprint(r.Key) + "\x00" + k
+	}
+}
+
+// call is a request that is being handled, which repeated requests wait for
+type call[U any] struct {
+	done chan struct{}
+	u    *U
+	err  error
+}
+
+// Handler returns a Handler that runs h at most once for each idempotency key returned by key, with
+// repeated requests receiving the stored response and error.  Requests for which key returns an empty
+// string are always passed to h.  A repeated request that arrives whilst the first is still being
+// handled waits for its result.
+//
+// The results of requests that are cancelled, that panic, or whose error is Retryable are not stored,
+// so that a retry runs h again.  Stored responses may be returned to more than one caller, and so
+// must not be modified.
+func Handler[T, U any](store Store[U], key func(ctx context.Context, t *T) string, h types.Handler[T, U], opts ...func(*Options)) types.Handler[T, U] {
+	o := Options{
+		TTL:   24 * time.Hour,
+		Clock: saferr.RealClock(),
+		// Replaced by the TTL unless set
+		PurgeInterval: -1,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.PurgeInterval < 0 {
+		o.PurgeInterval = o.TTL
+	}
+
+	storeError := func(key string, err error) {
+		if err != nil && o.OnStoreError != nil {
+			o.OnStoreError(key, err)
+		}
+	}
+
+	var (
+		lck       sync.Mutex
+		inflight  = map[string]*call[U]{}
+		lastPurge = o.Clock.Now()
+		purging   atomic.Bool
+	)
+
+	purge := func(now time.Time) {
+		if o.PurgeInterval <= 0 {
+			return
+		}
+		lck.Lock()
+		due := now.Sub(lastPurge) >= o.PurgeInterval
+		if due {
+			lastPurge = now
+		}
+		lck.Unlock()
+
+		if due && purging.CompareAndSwap(false, true) {
+			go func() {
+				defer purging.Store(false)
+				_, err := store.Purge(now)
+				storeError("", err)
+			}()
+		}
+	}
+
+	return func(ctx context.Context, t *T) (*U, error) {
+		k := key(ctx, t)
+		if k == "" {
+			return h(ctx, t)
+		}
+
+		now := o.Clock.Now()
+		purge(now)
+
+		lck.Lock()
+		if c, ok := inflight[k]; ok {
+			lck.Unlock()
+			select {
+			case <-c.done:
+				return c.u, c.err
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		c := &call[U]{done: make(chan struct{})}
+		inflight[k] = c
+		lck.Unlock()
+
+		defer func() {
+			lck.Lock()
+			delete(inflight, k)
+			lck.Unlock()
+			close(c.done)
+		}()
+
+		r, ok, err := store.Get(k)
+		if err != nil {
+			c.err = fmt.Errorf("%w: %w", ErrStore, err)
+			return nil, c.err
+		}
+		if ok {
+			if now.Before(r.Expires) {
+				c.u = r.Response
+				if r.Err != nil {
+					c.err = r.Err
+				}
+				return c.u, c.err
+			}
+			storeError(k, store.Delete(k))
+		}
+
+		// A panic is passed on to the Responder, with waiting requests receiving an error
+		c.err = fmt.Errorf("%w: idempotency key %q", saferr.ErrUncaughtHandlerPanic, k)
+		c.u, c.err = h(ctx, t)
+
+		if ctx.Err() == nil && !types.IsRetryable(c.err) {
+			storeError(k, store.Put(k, &Result[U]{
+				Response: c.u,
+				Err:      types.AsError(c.err),
+				Expires:  o.Clock.Now().Add(o.TTL),
+			}))
+		}
+		return c.u, c.err
+	}
+}
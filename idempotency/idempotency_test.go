@@ -0,0 +1,310 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gford1000-go/saferr"
+	"github.com/gford1000-go/saferr/types"
+)
+
+type payment struct {
+	Key    string
+	Amount int
+}
+
+func paymentKey(_ context.Context, p *payment) string {
+	return p.Key
+}
+
+func ExampleHandler() {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	balance := 0
+	charge := func(ctx context.Context, p *payment) (*int, error) {
+		balance += p.Amount
+		b := balance
+		return &b, nil
+	}
+
+	requestor := saferr.Go(ctx, Handler(NewMemoryStore[int](), paymentKey, charge))
+
+	// The retry receives the original response, and the payment is not taken twice
+	b, _ := requestor.Send(ctx, &payment{Key: "order-1", Amount: 10})
+	fmt.Println(*b)
+	b, _ = requestor.Send(ctx, &payment{Key: "order-1", Amount: 10})
+	fmt.Println(*b)
+	b, _ = requestor.Send(ctx, &payment{Key: "order-2", Amount: 5})
+	fmt.Println(*b)
+
+	// Output:
+	// 10
+	// 10
+	// 15
+}
+
+func ExampleMetaKey() {
+
+	type meta struct {
+		IdempotencyKey string
+	}
+
+	calls := 0
+	h := func(ctx context.Context, r *types.Request[string, meta, string]) (*int, error) {
+		calls++
+		n := calls
+		return &n, nil
+	}
+
+	handler := Handler(NewMemoryStore[int](), MetaKey[string, meta, string](func(m meta) string { return m.IdempotencyKey }), h)
+
+	ctx := context.Background()
+	data := "x"
+	a, _ := handler(ctx, &types.Request[string, meta, string]{Key: "create", Meta: meta{"k1"}, Data: &data})
+	b, _ := handler(ctx, &types.Request[string, meta, string]{Key: "create", Meta: meta{"k1"}, Data: &data})
+	c, _ := handler(ctx, &types.Request[string, meta, string]{Key: "delete", Meta: meta{"k1"}, Data: &data})
+	d, _ := handler(ctx, &types.Request[string, meta, string]{Key: "create", Data: &data})
+	fmt.Println(*a, *b, *c, *d)
+
+	// Output:
+	// 1 1 2 3
+}
+
+func TestHandler_TTL(t *testing.T) {
+
+	clock := saferr.NewFakeClock(time.Now())
+	store := NewMemoryStore[int]()
+
+	var calls atomic.Int32
+	h := func(ctx context.Context, p *payment) (*int, error) {
+		n := int(calls.Add(1))
+		return &n, nil
+	}
+
+	handler := Handler(store, paymentKey, h, WithTTL(time.Minute), WithClock(clock), WithPurgeInterval(0))
+
+	ctx := context.Background()
+	for range 3 {
+		if u, _ := handler(ctx, &payment{Key: "a"}); *u != 1 {
+			t.Fatalf("expected stored response 1, got %d", *u)
+		}
+	}
+
+	clock.Advance(time.Minute)
+
+	if u, _ := handler(ctx, &payment{Key: "a"}); *u != 2 {
+		t.Fatalf("expected handler to run again after TTL, got %d", *u)
+	}
+	if store.Len() != 1 {
+		t.Fatalf("expected 1 stored result, got %d", store.Len())
+	}
+}
+
+func TestHandler_Errors(t *testing.T) {
+
+	errDeclined := &types.Error{Code: types.CodeFailedPrecondition, Message: "declined"}
+
+	var calls atomic.Int32
+	h := func(ctx context.Context, p *payment) (*int, error) {
+		switch calls.Add(1) {
+		case 1:
+			return nil, saferr.ErrSendTimeout
+		case 2:
+			return nil, fmt.Errorf("%w: card expired", errDeclined)
+		}
+		t.Fatal("handler called after non-retryable error")
+		return nil, nil
+	}
+
+	handler := Handler(NewMemoryStore[int](), paymentKey, h)
+
+	ctx := context.Background()
+
+	// Retryable errors are not stored
+	if _, err := handler(ctx, &payment{Key: "a"}); !errors.Is(err, saferr.ErrSendTimeout) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for range 2 {
+		_, err := handler(ctx, &payment{Key: "a"})
+		if !errors.Is(err, errDeclined) {
+			t.Fatalf("expected stored error, got %v", err)
+		}
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("expected 2 calls, got %d", calls.Load())
+	}
+}
+
+func TestHandler_InFlight(t *testing.T) {
+
+	release := make(chan struct{})
+	var calls atomic.Int32
+	h := func(ctx context.Context, p *payment) (*int, error) {
+		n := int(calls.Add(1))
+		<-release
+		return &n, nil
+	}
+
+	handler := Handler(NewMemoryStore[int](), paymentKey, h)
+
+	ctx := context.Background()
+	results := make([]int, 5)
+
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			u, err := handler(ctx, &payment{Key: "a"})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			results[i] = *u
+		}()
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	for _, r := range results {
+		if r != 1 {
+			t.Fatalf("expected all requests to receive 1, got %v", results)
+		}
+	}
+}
+
+func TestHandler_Panic(t *testing.T) {
+
+	var calls atomic.Int32
+	h := func(ctx context.Context, p *payment) (*int, error) {
+		if calls.Add(1) == 1 {
+			panic("boom")
+		}
+		n := 1
+		return &n, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	requestor := saferr.Go(ctx, Handler(NewMemoryStore[int](), paymentKey, h))
+
+	if _, err := requestor.Send(ctx, &payment{Key: "a"}); !errors.Is(err, saferr.ErrUncaughtHandlerPanic) {
+		t.Fatalf("expected panic error, got %v", err)
+	}
+	if u, err := requestor.Send(ctx, &payment{Key: "a"}); err != nil || *u != 1 {
+		t.Fatalf("expected handler to run again after panic, got %v, %v", u, err)
+	}
+}
+
+type failingStore[U any] struct {
+	Store[U]
+}
+
+func (failingStore[U]) Get(key string) (*Result[U], bool, error) {
+	return nil, false, errors.New("disk on fire")
+}
+
+func TestHandler_StoreError(t *testing.T) {
+
+	h := func(ctx context.Context, p *payment) (*int, error) {
+		t.Fatal("handler should not run if the store cannot be read")
+		return nil, nil
+	}
+
+	handler := Handler(failingStore[int]{NewMemoryStore[int]()}, paymentKey, h)
+
+	_, err := handler(context.Background(), &payment{Key: "a"})
+	if !errors.Is(err, ErrStore) || !types.IsRetryable(err) {
+		t.Fatalf("expected retryable ErrStore, got %v", err)
+	}
+}
+
+func TestFileStore(t *testing.T) {
+
+	dir := t.TempDir()
+	clock := saferr.NewFakeClock(time.Now())
+
+	var calls atomic.Int32
+	h := func(ctx context.Context, p *payment) (*int, error) {
+		calls.Add(1)
+		if p.Amount < 0 {
+			return nil, &types.Error{Code: types.CodeInvalidArgument, Message: "negative amount"}
+		}
+		return &p.Amount, nil
+	}
+
+	store, err := NewFileStore[int](dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := Handler(store, paymentKey, h, WithClock(clock), WithTTL(time.Hour))
+
+	ctx := context.Background()
+	handler(ctx, &payment{Key: "a/../b", Amount: 7})
+	handler(ctx, &payment{Key: "neg", Amount: -1})
+
+	// A new store over the same directory, as after a restart
+	store, err = NewFileStore[int](dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler = Handler(store, paymentKey, h, WithClock(clock), WithTTL(time.Hour))
+
+	if u, err := handler(ctx, &payment{Key: "a/../b", Amount: 100}); err != nil || *u != 7 {
+		t.Fatalf("expected stored response 7, got %v, %v", u, err)
+	}
+	if _, err := handler(ctx, &payment{Key: "neg", Amount: -1}); types.CodeOf(err) != types.CodeInvalidArgument {
+		t.Fatalf("expected stored error, got %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("expected 2 calls, got %d", calls.Load())
+	}
+
+	n, err := store.Purge(clock.Now().Add(2 * time.Hour))
+	if err != nil || n != 2 {
+		t.Fatalf("expected 2 results purged, got %d, %v", n, err)
+	}
+	if _, ok, _ := store.Get("a/../b"); ok {
+		t.Fatal("expected result to be purged")
+	}
+}
+
+func TestMemoryStore_Purge(t *testing.T) {
+
+	clock := saferr.NewFakeClock(time.Now())
+	store := NewMemoryStore[int]()
+
+	h := func(ctx context.Context, p *payment) (*int, error) {
+		return &p.Amount, nil
+	}
+
+	handler := Handler(store, paymentKey, h, WithClock(clock), WithTTL(time.Minute))
+
+	ctx := context.Background()
+	for i := range 10 {
+		handler(ctx, &payment{Key: fmt.Sprint(i)})
+	}
+
+	// The next request after the interval starts a purge of the expired results
+	clock.Advance(time.Minute)
+	handler(ctx, &payment{Key: "new"})
+
+	deadline := time.Now().Add(time.Second)
+	for store.Len() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected expired results to be purged, %d remain", store.Len())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,215 @@
+package idempotency
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gford1000-go/saferr"
+	"github.com/gford1000-go/saferr/types"
+)
+
+// Result is the outcome of a request, stored against its idempotency key
+type Result[U any] struct {
+	// Response returned by the handler
+	Response *U
+	// Err returned by the handler, if any
+	Err *types.Error
+	// Expires is the time from which the Result is no longer used
+	Expires time.Time
+}
+
+// Store holds the Results of requests by idempotency key.  Implementations must be safe for concurrent use
+type Store[U any] interface {
+	// Get returns the Result stored for key, and false if there is none
+	Get(key string) (*Result[U], bool, error)
+	// Put stores the Result for key, replacing any existing Result
+	Put(key string, r *Result[U]) error
+	// Delete removes any Result stored for key
+	Delete(key string) error
+	// Purge removes all Results that have expired by t, returning the number removed
+	Purge(t time.Time) (int, error)
+}
+
+// MemoryStore is a Store held in memory
+type MemoryStore[U any] struct {
+	lck     sync.Mutex
+	results map[string]*Result[U]
+}
+
+// NewMemoryStore returns an empty MemoryStore
+func NewMemoryStore[U any]() *MemoryStore[U] {
+	return &MemoryStore[U]{results: map[string]*Result[U]{}}
+}
+
+// Get returns the Result stored for key, and false if there is none
+func (s *MemoryStore[U]) Get(key string) (*Result[U], bool, error) {
+	s.lck.Lock()
+	defer s.lck.Unlock()
+	r, ok := s.results[key]
+	return r, ok, nil
+}
+
+// Put stores the Result for key, replacing any existing Result
+func (s *MemoryStore[U]) Put(key string, r *Result[U]) error {
+	s.lck.Lock()
+	defer s.lck.Unlock()
+	s.results[key] = r
+	return nil
+}
+
+// Delete removes any Result stored for key
+func (s *MemoryStore[U]) Delete(key string) error {
+	s.lck.Lock()
+	defer s.lck.Unlock()
+	delete(s.results, key)
+	return nil
+}
+
+// Purge removes all Results that have expired by t, returning the number removed
+func (s *MemoryStore[U]) Purge(t time.Time) (int, error) {
+	s.lck.Lock()
+	defer s.lck.Unlock()
+
+	n := 0
+	for k, r := range s.results {
+		if !r.Expires.After(t) {
+			delete(s.results, k)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// Len returns the number of Results stored
+func (s *MemoryStore[U]) Len() int {
+	s.lck.Lock()
+	defer s.lck.Unlock()
+	return len(s.results)
+}
+
+const resultSuffix = ".result"
+
+// FileStore is a Store that holds each Result in its own file within a directory, so that
+// Results survive the process restarting
+type FileStore[U any] struct {
+	dir   string
+	codec saferr.Codec[Result[U]]
+	lck   sync.Mutex
+}
+
+// NewFileStore returns a FileStore using dir, which is created if necessary.  Results are encoded
+// using codec, or saferr.JSONCodec if codec is nil
+func NewFileStore[U any](dir string, codec saferr.Codec[Result[U]]) (*FileStore[U], error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	if codec == nil {
+		codec = saferr.JSONCodec[Result[U]]{}
+	}
+	return &FileStore[U]{dir: dir, codec: codec}, nil
+}
+
+// path returns the file for key, hashed so that any key can be used safely as a file name
+func (s *FileStore[U]) path(key string) string {
+	h := sha256.Sum256([]byte(key))
+	return filepath.Join(s.dir, hex.EncodeToString(h[:])+resultSuffix)
+}
+
+// Get returns the Result stored for key, and false if there is none
+func (s *FileStore[U]) Get(key string) (*Result[U], bool, error) {
+	data, err := os.ReadFile(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	r, err := s.codec.Unmarshal(data)
+	if err != nil {
+		return nil, false, err
+	}
+	return r, true, nil
+}
+
+// Put stores the Result for key, replacing any existing Result.  The file is written
+// atomically, so that a partially written Result is never read
+func (s *FileStore[U]) Put(key string, r *Result[U]) error {
+	data, err := s.codec.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(s.dir, "tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	s.lck.Lock()
+	defer s.lck.Unlock()
+	return os.Rename(f.Name(), s.path(key))
+}
+
+// Delete removes any Result stored for key
+func (s *FileStore[U]) Delete(key string) error {
+	s.lck.Lock()
+	defer s.lck.Unlock()
+
+	if err := os.Remove(s.path(key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Purge removes all Results that have expired by t, returning the number removed.  Files
+// that cannot be decoded are also removed
+func (s *FileStore[U]) Purge(t time.Time) (int, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return 0, err
+	}
+
+	s.lck.Lock()
+	defer s.lck.Unlock()
+
+	n := 0
+	var errs []error
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), resultSuffix) {
+			continue
+		}
+		path := filepath.Join(s.dir, e.Name())
+
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if r, err := s.codec.Unmarshal(data); err == nil && r.Expires.After(t) {
+			continue
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+			continue
+		}
+		n++
+	}
+	return n, errors.Join(errs...)
+}
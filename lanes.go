@@ -0,0 +1,92 @@
+package saferr
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gford1000-go/saferr/types"
+)
+
+// lanes holds the queues of a Requestor / Responder pair that has more than one priority lane.
+// Lane 0 has the highest priority.  Only the Responder takes requests from the lanes, and it
+// does so from a single goroutine, so the starvation counts need no locking
+type lanes[T any, U any] struct {
+	ch     []chan *req[T, U]
+	signal chan struct{}
+	// skipped counts, for each lane, the requests taken from higher priority lanes whilst it
+	// had requests waiting
+	skipped      []int
+	starvedLimit int
+}
+
+func newLanes[T any, U any](sizes []int, starvedLimit int) *lanes[T, U] {
+	l := &lanes[T, U]{
+		ch:           make([]chan *req[T, U], len(sizes)),
+		skipped:      make([]int, len(sizes)),
+		starvedLimit: starvedLimit,
+		signal:       make(chan struct{}, 1),
+	}
+	for i, size := range sizes {
+		l.ch[i] = make(chan *req[T, U], size)
+	}
+	return l
+}
+
+// notify signals that a request has been added to a lane
+func (l *lanes[T, U]) notify() {
+	notify(l.signal)
+}
+
+// ready signals that a request may have been added to a lane
+func (l *lanes[T, U]) ready() <-chan struct{} {
+	return l.signal
+}
+
+// next takes the next request to be handled without blocking, returning nil if all lanes are empty.
+// Lanes are served in strict priority, except that a lane which has been passed over starvedLimit
+// times whilst it had requests waiting is served next
+func (l *lanes[T, U]) next() *req[T, U] {
+	for i, n := range l.skipped {
+		if n >= l.starvedLimit {
+			if req := l.take(i); req != nil {
+				return req
+			}
+		}
+	}
+	for i := range l.ch {
+		if req := l.take(i); req != nil {
+			return req
+		}
+	}
+	return nil
+}
+
+// take returns the next request from lane i, if there is one
+func (l *lanes[T, U]) take(i int) *req[T, U] {
+	select {
+	case req := <-l.ch[i]:
+		l.skipped[i] = 0
+		for j := i + 1; j < len(l.ch); j++ {
+			if len(l.ch[j]) > 0 {
+				l.skipped[j]++
+			}
+		}
+		return req
+	default:
+		return nil
+	}
+}
+
+// SendWithPriority sends the request using the priority lane of the Requestor, where lane 0 has the
+// highest priority and the lanes are configured with WithLanes.  An error is returned if the lane
+// does not exist.  Requestors that do not support priority, such as those returned by wrappers of Go,
+// send the request using Send if the priority is 0.
+func SendWithPriority[T any, U any](ctx context.Context, r types.Requestor[T, U], priority int, t *T) (*U, error) {
+	if pr, ok := r.(types.PriorityRequestor[T, U]); ok {
+		return pr.SendWithPriority(ctx, priority, t)
+	}
+	if priority != 0 {
+		return nil, fmt.Errorf("%w: %d, requestor has no priority lanes", ErrInvalidPriority, priority)
+	}
+	return r.Send(ctx, t)
+}
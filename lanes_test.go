@@ -0,0 +1,255 @@
+package saferr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gford1000-go/saferr/types"
+)
+
+// laneRecorder records the order in which requests are handled
+type laneRecorder struct {
+	lck     sync.Mutex
+	order   []string
+	release chan struct{}
+	started chan struct{}
+	first   sync.Once
+}
+
+func newLaneRecorder() *laneRecorder {
+	return &laneRecorder{release: make(chan struct{}), started: make(chan struct{})}
+}
+
+// block sends a request that is handled once released, so that others can be queued behind it
+func (l *laneRecorder) block(r types.Requestor[string, string], wg *sync.WaitGroup) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s := "block"
+		r.Send(context.Background(), &s)
+	}()
+	<-l.started
+}
+
+func (l *laneRecorder) handle(ctx context.Context, s *string) (*string, error) {
+	l.first.Do(func() {
+		close(l.started)
+		<-l.release
+	})
+	l.lck.Lock()
+	defer l.lck.Unlock()
+	l.order = append(l.order, *s)
+	return s, nil
+}
+
+// queue sends each request in its own goroutine, waiting until it is queued before sending the next,
+// so that the sequence within each lane is known
+func queue(t *testing.T, r types.Requestor[string, string], wg *sync.WaitGroup, priority int, names ...string) {
+	t.Helper()
+	l := r.(*requestor[string, string]).lanes
+	for _, name := range names {
+		before := len(l.ch[priority])
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := SendWithPriority(context.Background(), r, priority, &name); err != nil {
+				t.Error(err)
+			}
+		}()
+		deadline := time.Now().Add(time.Second)
+		for len(l.ch[priority]) == before {
+			if time.Now().After(deadline) {
+				t.Fatalf("request %s not queued", name)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+}
+
+func ExampleSendWithPriority() {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const (
+		health = iota
+		user
+		bulk
+	)
+
+	handler := func(ctx context.Context, s *string) (*string, error) {
+		r := "handled " + *s
+		return &r, nil
+	}
+
+	requestor := Go(ctx, handler, WithLanes(10, 100, 1000), WithDefaultLane(user))
+
+	ping, login, export := "ping", "login", "export"
+
+	r, _ := SendWithPriority(ctx, requestor, health, &ping)
+	fmt.Println(*r)
+	r, _ = requestor.Send(ctx, &login)
+	fmt.Println(*r)
+	_, err := SendWithPriority(ctx, requestor, bulk+1, &export)
+	fmt.Println(err)
+
+	// Output:
+	// handled ping
+	// handled login
+	// invalid priority: 3
+}
+
+func TestLanes_StrictPriority(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rec := newLaneRecorder()
+	requestor := Go(ctx, rec.handle, WithLanes(10, 10, 10), WithLaneStarvationLimit(100))
+
+	var wg sync.WaitGroup
+	rec.block(requestor, &wg)
+
+	queue(t, requestor, &wg, 2, "bulk-1", "bulk-2")
+	queue(t, requestor, &wg, 1, "user-1", "user-2")
+	queue(t, requestor, &wg, 0, "health")
+	close(rec.release)
+	wg.Wait()
+
+	want := []string{"block", "health", "user-1", "user-2", "bulk-1", "bulk-2"}
+	if !reflect.DeepEqual(rec.order, want) {
+		t.Fatalf("expected %v, got %v", want, rec.order)
+	}
+}
+
+func TestLanes_Starvation(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rec := newLaneRecorder()
+	requestor := Go(ctx, rec.handle, WithLanes(10, 10), WithLaneStarvationLimit(2))
+
+	var wg sync.WaitGroup
+	rec.block(requestor, &wg)
+
+	queue(t, requestor, &wg, 1, "low-1", "low-2")
+	queue(t, requestor, &wg, 0, "high-1", "high-2", "high-3", "high-4", "high-5")
+	close(rec.release)
+	wg.Wait()
+
+	want := []string{"block", "high-1", "high-2", "low-1", "high-3", "high-4", "low-2", "high-5"}
+	if !reflect.DeepEqual(rec.order, want) {
+		t.Fatalf("expected %v, got %v", want, rec.order)
+	}
+}
+
+func TestLanes_Workers(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	handler := func(ctx context.Context, n *int) (*int, error) {
+		m := *n * 2
+		return &m, nil
+	}
+
+	requestor := Go(ctx, handler, WithLanes(100, 100, 100), WithWorkers(4))
+
+	var wg sync.WaitGroup
+	for i := range 300 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			u, err := SendWithPriority(ctx, requestor, i%3, &i)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if *u != i*2 {
+				t.Errorf("expected %d, got %d", i*2, *u)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestSendWithPriority_NoLanes(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	requestor := Go(ctx, func(ctx context.Context, s *string) (*string, error) { return s, nil })
+
+	a := "a"
+	if u, err := SendWithPriority(ctx, requestor, 0, &a); err != nil || *u != "a" {
+		t.Fatalf("unexpected result: %v, %v", u, err)
+	}
+	if _, err := SendWithPriority(ctx, requestor, 1, &a); !errors.Is(err, ErrInvalidPriority) {
+		t.Fatalf("expected ErrInvalidPriority, got %v", err)
+	}
+
+	// A Requestor that does not support priority lanes
+	var plain types.Requestor[string, string] = struct {
+		types.Requestor[string, string]
+	}{requestor}
+	if _, err := SendWithPriority(ctx, plain, 2, &a); !errors.Is(err, ErrInvalidPriority) {
+		t.Fatalf("expected ErrInvalidPriority, got %v", err)
+	}
+}
+
+func TestLanes_DefaultLane(t *testing.T) {
+
+	tests := []struct {
+		opts []func(*Options)
+		lane int
+	}{
+		{[]func(*Options){WithLanes(10, 10, 10)}, 2},
+		{[]func(*Options){WithLanes(10, 10, 10), WithDefaultLane(1)}, 1},
+		{[]func(*Options){WithLanes(10, 10, 10), WithDefaultLane(5)}, 2},
+	}
+
+	for i, test := range tests {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		rec := newLaneRecorder()
+		r := Go(ctx, rec.handle, test.opts...)
+
+		var wg sync.WaitGroup
+		rec.block(r, &wg)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s := "send"
+			r.Send(ctx, &s)
+		}()
+
+		l := r.(*requestor[string, string]).lanes
+		deadline := time.Now().Add(time.Second)
+		for len(l.ch[test.lane]) == 0 {
+			if time.Now().After(deadline) {
+				t.Fatalf("%d: request not queued in lane %d", i, test.lane)
+			}
+			time.Sleep(time.Millisecond)
+		}
+
+		close(rec.release)
+		wg.Wait()
+		cancel()
+	}
+}
+
+func TestWithLanes_Invalid(t *testing.T) {
+
+	o := defaults
+	WithLanes(10, 0, 10)(&o)
+	if o.Lanes != nil {
+		t.Fatalf("expected the option to be ignored, got %v", o.Lanes)
+	}
+}
@@ -0,0 +1,254 @@
+// Package loader provides client-side auto-batching: keys requested by concurrent callers are
+// collected for a short time, deduplicated, and sent as a single request to a Responder that
+// loads many entities at once.  Each caller receives its own value, or an error for its key.
+package loader
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gford1000-go/saferr"
+	"github.com/gford1000-go/saferr/types"
+)
+
+// ErrNotFound is returned for a key that is absent from the response to its batch
+var ErrNotFound = &types.Error{Code: types.CodeNotFound, Message: "key not found"}
+
+// Options configure a Loader
+type Options struct {
+	// Wait is how long keys are collected after the first, before the batch is sent.  Default: 1ms
+	Wait time.Duration
+	// MaxBatchSize is the maximum number of distinct keys in a batch, which is sent as soon as it is full.  Default: 100
+	MaxBatchSize int
+	// Clock provides the timers used for Wait.  Defaults to the wall clock
+	Clock saferr.Clock
+}
+
+// WithWait sets how long keys are collected after the first, before the batch is sent
+func WithWait(d time.Duration) func(*Options) {
+	return func(o *Options) {
+		if d > 0 {
+			o.Wait = d
+		}
+	}
+}
+
+// WithMaxBatchSize sets the maximum number of distinct keys in a batch
+func WithMaxBatchSize(n int) func(*Options) {
+	return func(o *Options) {
+		if n > 0 {
+			o.MaxBatchSize = n
+		}
+	}
+}
+
+// WithClock sets the Clock used for Wait, allowing a saferr.FakeClock to be used in tests
+func WithClock(c saferr.Clock) func(*Options) {
+	return func(o *Options) {
+		if c != nil {
+			o.Clock = c
+		}
+	}
+}
+
+// batch collects the keys to be sent together
+type batch[K comparable, V any] struct {
+	keys   []K
+	seen   map[K]bool
+	full   chan struct{}
+	done   chan struct{}
+	values map[K]*V
+	err    error
+}
+
+// Loader batches the keys requested by concurrent callers into single requests, sent using a Requestor
+// whose handler returns the values of all the keys it is able to load
+type Loader[K comparable, V any] struct {
+	ctx       context.Context
+	requestor types.Requestor[[]K, map[K]*V]
+	o         Options
+	lck       sync.Mutex
+	current   *batch[K, V]
+}
+
+// New returns a Loader that sends batches of keys using requestor.  Batches are sent using ctx, rather than
+// the contexts of the callers, since they are shared between callers
+func New[K comparable, V any](ctx context.Context, requestor types.Requestor[[]K, map[K]*V], opts ...func(*Options)) *Loader[K, V] {
+	o := Options{
+		Wait:         time.Millisecond,
+		MaxBatchSize: 100,
+		Clock:        saferr.RealClock(),
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Loader[K, V]{ctx: ctx, requestor: requestor, o: o}
+}
+
+// Load returns the value of key, which is sent in a batch with the keys of other callers.  If the batch
+// request fails, each of its callers receives the error; if the response has no value for key, ErrNotFound
+// is returned.  If ctx was returned by WithCache, a value already loaded in that scope is reused
+func (l *Loader[K, V]) Load(ctx context.Context, key K) (*V, error) {
+	if c, ok := ctx.Value(l).(*cache[K, V]); ok {
+		return c.load(ctx, l, key)
+	}
+	return l.load(ctx, key)
+}
+
+// LoadMany returns the values of keys, in the same sequence, with an error for each key that could not be loaded
+func (l *Loader[K, V]) LoadMany(ctx context.Context, keys []K) ([]*V, []error) {
+	values := make([]*V, len(keys))
+	errs := make([]error, len(keys))
+
+	var wg sync.WaitGroup
+	for i, key := range keys {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			values[i], errs[i] = l.Load(ctx, key)
+		}()
+	}
+	wg.Wait()
+	return values, errs
+}
+
+func (l *Loader[K, V]) load(ctx context.Context, key K) (*V, error) {
+	b := l.add(key)
+
+	select {
+	case <-b.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	if b.err != nil {
+		return nil, b.err
+	}
+	v, ok := b.values[key]
+	if !ok {
+		return nil, fmt.Errorf("%w: %v", ErrNotFound, key)
+	}
+	return v, nil
+}
+
+// add includes key in the batch being collected, starting a new batch if there is none
+func (l *Loader[K, V]) add(key K) *batch[K, V] {
+	l.lck.Lock()
+	defer l.lck.Unlock()
+
+	b := l.current
+	if b == nil {
+		b = &batch[K, V]{
+			seen: map[K]bool{},
+			full: make(chan struct{}),
+			done: make(chan struct{}),
+		}
+		l.current = b
+		go l.wait(b)
+	}
+
+	if !b.seen[key] {
+		b.seen[key] = true
+		b.keys = append(b.keys, key)
+	}
+
+	if len(b.keys) >= l.o.MaxBatchSize {
+		l.current = nil
+		close(b.full)
+		go l.send(b)
+	}
+	return b
+}
+
+// wait sends the batch once Wait has passed, unless it has already been sent because it was full
+func (l *Loader[K, V]) wait(b *batch[K, V]) {
+	timer := l.o.Clock.NewTimer(l.o.Wait)
+	defer timer.Stop()
+
+	select {
+	case <-b.full:
+		return
+	case <-timer.C():
+	}
+
+	l.lck.Lock()
+	if l.current != b {
+		l.lck.Unlock()
+		return
+	}
+	l.current = nil
+	l.lck.Unlock()
+
+	l.send(b)
+}
+
+func (l *Loader[K, V]) send(b *batch[K, V]) {
+	defer close(b.done)
+
+	defer func() {
+		if rc := recover(); rc != nil {
+			b.err = fmt.Errorf("%w: %v", saferr.ErrUncaughtSendPanic, rc)
+		}
+	}()
+
+	values, err := l.requestor.Send(l.ctx, &b.keys)
+	if err != nil {
+		b.err = err
+		return
+	}
+	if values != nil {
+		b.values = *values
+	}
+}
+
+// cacheEntry is the value of a key loaded within a cache scope, which is available once done is closed
+type cacheEntry[V any] struct {
+	done chan struct{}
+	v    *V
+	err  error
+}
+
+// cache holds the values loaded within the scope of a context returned by WithCache
+type cache[K comparable, V any] struct {
+	lck     sync.Mutex
+	entries map[K]*cacheEntry[V]
+}
+
+// WithCache returns a context within which each key is loaded at most once by this Loader, for example
+// for the duration of handling an incoming request.  Errors are not cached, so a key that could not be
+// loaded is tried again by the next call to Load
+func (l *Loader[K, V]) WithCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, l, &cache[K, V]{entries: map[K]*cacheEntry[V]{}})
+}
+
+func (c *cache[K, V]) load(ctx context.Context, l *Loader[K, V], key K) (*V, error) {
+	c.lck.Lock()
+	e, ok := c.entries[key]
+	if !ok {
+		e = &cacheEntry[V]{done: make(chan struct{})}
+		c.entries[key] = e
+	}
+	c.lck.Unlock()
+
+	if !ok {
+		// The load is shared with later callers, so it is not cancelled if the first caller is
+		go func() {
+			e.v, e.err = l.load(context.WithoutCancel(ctx), key)
+			if e.err != nil {
+				c.lck.Lock()
+				delete(c.entries, key)
+				c.lck.Unlock()
+			}
+			close(e.done)
+		}()
+	}
+
+	select {
+	case <-e.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return e.v, e.err
+}
@@ -0,0 +1,233 @@
+package loader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gford1000-go/saferr"
+)
+
+type user struct {
+	Name string
+}
+
+// userStore loads users by id, recording the batches of ids it receives
+type userStore struct {
+	lck     sync.Mutex
+	batches [][]int
+	err     error
+}
+
+func (s *userStore) load(ctx context.Context, ids *[]int) (*map[int]*user, error) {
+	s.lck.Lock()
+	defer s.lck.Unlock()
+
+	s.batches = append(s.batches, slices.Clone(*ids))
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	users := map[int]*user{}
+	for _, id := range *ids {
+		if id >= 0 {
+			users[id] = &user{Name: fmt.Sprint("user-", id)}
+		}
+	}
+	return &users, nil
+}
+
+func ExampleLoader() {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := &userStore{}
+	loader := New(ctx, saferr.Go(ctx, store.load), WithWait(50*time.Millisecond))
+
+	// Concurrent callers, such as the handlers of separate API requests
+	var wg sync.WaitGroup
+	for _, id := range []int{3, 1, 3, 2} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			loader.Load(ctx, id)
+		}()
+	}
+	wg.Wait()
+
+	// Sent as a single batch, with the duplicate removed
+	slices.Sort(store.batches[0])
+	fmt.Println(len(store.batches), store.batches[0])
+
+	// Output:
+	// 1 [1 2 3]
+}
+
+func TestLoader_MaxBatchSize(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := &userStore{}
+	loader := New(ctx, saferr.Go(ctx, store.load), WithWait(time.Hour), WithMaxBatchSize(3))
+
+	// The Wait is never reached, so batches are only sent when full
+	ids := []int{1, 2, 3, 4, 5, 6}
+	users, errs := loader.LoadMany(ctx, ids)
+	for i, id := range ids {
+		if errs[i] != nil || users[i].Name != fmt.Sprint("user-", id) {
+			t.Fatalf("unexpected result for %d: %v, %v", id, users[i], errs[i])
+		}
+	}
+
+	if len(store.batches) != 2 {
+		t.Fatalf("expected 2 batches, got %v", store.batches)
+	}
+	for _, b := range store.batches {
+		if len(b) != 3 {
+			t.Fatalf("expected batches of 3, got %v", store.batches)
+		}
+	}
+}
+
+func TestLoader_Errors(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := &userStore{}
+	loader := New(ctx, saferr.Go(ctx, store.load))
+
+	users, errs := loader.LoadMany(ctx, []int{1, -1})
+	if errs[0] != nil || users[0] == nil {
+		t.Fatalf("unexpected result: %v, %v", users[0], errs[0])
+	}
+	if !errors.Is(errs[1], ErrNotFound) || users[1] != nil {
+		t.Fatalf("expected ErrNotFound, got %v, %v", users[1], errs[1])
+	}
+
+	errDown := errors.New("database unavailable")
+	store.lck.Lock()
+	store.err = errDown
+	store.lck.Unlock()
+
+	_, errs = loader.LoadMany(ctx, []int{1, 2})
+	for _, err := range errs {
+		if !errors.Is(err, errDown) {
+			t.Fatalf("expected batch error, got %v", err)
+		}
+	}
+}
+
+func TestLoader_Cache(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := &userStore{}
+	loader := New(ctx, saferr.Go(ctx, store.load))
+
+	scope := loader.WithCache(ctx)
+
+	a, _ := loader.Load(scope, 1)
+	b, _ := loader.Load(scope, 1)
+	if a != b || len(store.batches) != 1 {
+		t.Fatalf("expected cached value, got %d batches", len(store.batches))
+	}
+
+	// A missing key is not cached
+	loader.Load(scope, -1)
+	loader.Load(scope, -1)
+	if len(store.batches) != 3 {
+		t.Fatalf("expected 3 batches, got %d", len(store.batches))
+	}
+
+	// Another scope has its own cache
+	if c, _ := loader.Load(loader.WithCache(ctx), 1); c == a {
+		t.Fatal("expected value to be loaded again in a new scope")
+	}
+	if len(store.batches) != 4 {
+		t.Fatalf("expected 4 batches, got %d", len(store.batches))
+	}
+}
+
+func TestLoader_Wait(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	clock := saferr.NewFakeClock(time.Now())
+	store := &userStore{}
+	loader := New(ctx, saferr.Go(ctx, store.load), WithWait(time.Second), WithClock(clock))
+
+	done := make(chan *user)
+	go func() {
+		u, _ := loader.Load(ctx, 7)
+		done <- u
+	}()
+
+	clock.BlockUntil(1)
+	select {
+	case <-done:
+		t.Fatal("batch sent before Wait")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	clock.Advance(time.Second)
+	if u := <-done; u.Name != "user-7" {
+		t.Fatalf("unexpected user %v", u)
+	}
+}
+
+func TestLoader_CallerCancelled(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := &userStore{}
+	loader := New(ctx, saferr.Go(ctx, store.load), WithWait(50*time.Millisecond))
+
+	callerCtx, callerCancel := context.WithCancel(ctx)
+	callerCancel()
+
+	if _, err := loader.Load(callerCtx, 1); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	// Other callers of the batch are unaffected
+	if u, err := loader.Load(ctx, 1); err != nil || u.Name != "user-1" {
+		t.Fatalf("unexpected result: %v, %v", u, err)
+	}
+}
+
+func TestLoader_CacheCallerCancelled(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := &userStore{}
+	loader := New(ctx, saferr.Go(ctx, store.load), WithWait(50*time.Millisecond))
+
+	scope := loader.WithCache(ctx)
+
+	// The first caller gives up whilst the key is being loaded
+	first, firstCancel := context.WithTimeout(scope, 10*time.Millisecond)
+	defer firstCancel()
+
+	if _, err := loader.Load(first, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+
+	// Later callers in the scope receive the value, rather than the error of the first caller
+	if u, err := loader.Load(scope, 1); err != nil || u.Name != "user-1" {
+		t.Fatalf("unexpected result: %v, %v", u, err)
+	}
+	if len(store.batches) != 1 {
+		t.Fatalf("expected 1 batch, got %d", len(store.batches))
+	}
+}
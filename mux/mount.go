@@ -0,0 +1,138 @@
+package mux
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gford1000-go/saferr/types"
+)
+
+// Mount associates a Key prefix with a sub-router, so that Requests whose Key starts with the Prefix
+// are passed to the sub-router with the Prefix removed from their Key.  The sub-router keeps its own
+// resolver, NotFound behaviour and per-route settings
+type Mount[T, U, M any] struct {
+	// Prefix of the Keys handled by the sub-router, for example "billing/".  An empty Prefix
+	// matches all Keys, and so is only used if no other Prefix matches
+	Prefix string
+	// Handler is the sub-router, which may itself be created by NewMountHandler
+	Handler *Handler[T, U, M, string]
+	// Middleware wraps every route dispatched to the sub-router, with the first Middleware being the
+	// outermost, and runs before the Middleware of the route itself
+	Middleware []Middleware[T, U]
+}
+
+type routePathKey struct{}
+
+type mountRequestKey struct{}
+
+// mounted is a Mount with its Middleware applied
+type mounted[T, U any] struct {
+	prefix  string
+	handler types.Handler[T, U]
+}
+
+// dispatch returns the Handler of the Mount, which passes the Request found in the context to the
+// sub-router, so that the Middleware is applied once rather than for each Request
+func (m *Mount[T, U, M]) dispatch() types.Handler[T, U] {
+	sub := m.Handler
+	prefix := m.Prefix
+
+	var h types.Handler[T, U] = func(ctx context.Context, t *T) (*U, error) {
+		r := ctx.Value(mountRequestKey{}).(*types.Request[T, M, string])
+		sr := *r
+		sr.Key = r.Key[len(prefix):]
+		sr.Data = t
+		return sub.Handler(ctx, &sr)
+	}
+	for i := len(m.Middleware) - 1; i >= 0; i-- {
+		h = m.Middleware[i](h)
+	}
+	return h
+}
+
+// routePath accumulates the matched route as a Request passes through nested Handlers
+type routePath struct {
+	path strings.Builder
+}
+
+// RoutePath returns the route matched for the Request being handled, being the Prefixes of the Mounts
+// followed by the Key matched by the sub-router (such as a pattern, or the Key resolved by a KeyResolver).
+// An empty string is returned if the Handler was not invoked via NewMountHandler
+func RoutePath(ctx context.Context) string {
+	if rp, ok := ctx.Value(routePathKey{}).(*routePath); ok {
+		return rp.path.String()
+	}
+	return ""
+}
+
+// appendRoutePath adds the matched key to the route path in the context, if there is one
+func appendRoutePath[K comparable](ctx context.Context, key K) {
+	if rp, ok := ctx.Value(routePathKey{}).(*routePath); ok {
+		if s, ok := any(key).(string); ok {
+			rp.path.WriteString(s)
+		} else {
+			fmt.Fprint(&rp.path, key)
+		}
+	}
+}
+
+// NewMountHandler initialises a new Handler instance that composes sub-routers, each handling the Keys
+// with its Prefix.  Where more than one Prefix matches a Key, the longest is used.
+// The matched route is available to the Handlers using RoutePath(ctx), for example for logging.
+// An error is returned if a Mount has a nil Handler, or if a Prefix is used more than once.
+func NewMountHandler[T, U, M any](mounts ...*Mount[T, U, M]) (*Handler[T, U, M, string], error) {
+
+	var sorted []mounted[T, U]
+	var keys []string
+	var info []RouteInfo[string]
+	seen := map[string]bool{}
+
+	for _, m := range mounts {
+		if m.Handler == nil || m.Handler.Handler == nil {
+			return nil, fmt.Errorf("%w: prefix %q", ErrNilHandler, m.Prefix)
+		}
+		if seen[m.Prefix] {
+			return nil, fmt.Errorf("%w: prefix %q", ErrDuplicateKey, m.Prefix)
+		}
+		seen[m.Prefix] = true
+		keys = append(keys, m.Prefix)
+		sorted = append(sorted, mounted[T, U]{prefix: m.Prefix, handler: m.dispatch()})
+
+		for _, r := range m.Handler.Routes() {
+			r.Key = m.Prefix + r.Key
+			if r.Targets != nil {
+				targets := make([]string, len(r.Targets))
+				for i, t := range r.Targets {
+					targets[i] = m.Prefix + t
+				}
+				r.Targets = targets
+			}
+			info = append(info, r)
+		}
+	}
+
+	// Longest Prefix first, so that the most specific sub-router is found
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return len(sorted[i].prefix) > len(sorted[j].prefix)
+	})
+
+	lookup := func(r *types.Request[T, M, string]) (types.Handler[T, U], string, bool) {
+		for _, m := range sorted {
+			if !strings.HasPrefix(r.Key, m.prefix) {
+				continue
+			}
+			h := m.handler
+			return func(ctx context.Context, t *T) (*U, error) {
+				return h(context.WithValue(ctx, mountRequestKey{}, r), t)
+			}, m.prefix, true
+		}
+		return nil, r.Key, false
+	}
+
+	h := newHandler[T, U, M](keys, nil, lookup, nil)
+	h.routes = info
+	h.mount = true
+	return h, nil
+}
@@ -0,0 +1,264 @@
+package mux
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gford1000-go/saferr/types"
+)
+
+// ErrDuplicateKey is returned if a Handler is registered against a Key that already has a Handler
+var ErrDuplicateKey = &types.Error{Code: types.CodeAlreadyExists, Message: "duplicate key"}
+
+// ErrNilHandler is returned if a Register does not provide a Handler
+var ErrNilHandler = &types.Error{Code: types.CodeInvalidArgument, Message: "nil handler"}
+
+// ChangeKind describes how the Handler for a Key has changed
+type ChangeKind int
+
+const (
+	// Registered indicates a Handler has been added for a Key
+	Registered ChangeKind = iota
+	// Unregistered indicates the Handler for a Key has been removed
+	Unregistered
+	// Replaced indicates the Handler for a Key has been swapped for a different Handler
+	Replaced
+)
+
+// String returns the name of the ChangeKind
+func (c ChangeKind) String() string {
+	switch c {
+	case Registered:
+		return "registered"
+	case Unregistered:
+		return "unregistered"
+	case Replaced:
+		return "replaced"
+	}
+	return fmt.Sprintf("changeKind(%d)", int(c))
+}
+
+// Change is the notification of a change to the Handlers of a MutableHandler
+type Change[K comparable] struct {
+	Kind ChangeKind
+	Key  K
+}
+
+// routes is a set of Handlers of a MutableHandler, together with the Keys whose Handlers need the Meta of
+// the Request, so that the Meta is only added to the context whilst such Handlers are present
+type routes[T, U any, K comparable] struct {
+	handlers map[K]types.Handler[T, U]
+	meta     map[K]bool
+}
+
+// set adds the Handler of the Register to the routes, replacing any Handler for its Key
+func (r *routes[T, U, K]) set(v *Register[T, U, K], x *exclusion) {
+	r.handlers[v.Key] = v.handler(x)
+	if v.needsMeta() {
+		r.meta[v.Key] = true
+	} else {
+		delete(r.meta, v.Key)
+	}
+}
+
+// remove deletes the Handler for the Key from the routes
+func (r *routes[T, U, K]) remove(k K) {
+	delete(r.handlers, k)
+	delete(r.meta, k)
+}
+
+// MutableHandler is a Handler whose set of Handlers can be changed whilst it is serving requests.
+// Changes are copy-on-write, so that finding the Handler for a Request never takes a lock.
+type MutableHandler[T, U, M any, K comparable] struct {
+	// Handler to present to Go() or Responder.ListenAndHandle()
+	Handler   func(ctx context.Context, t *types.Request[T, M, K]) (*U, error)
+	routes    atomic.Pointer[routes[T, U, K]]
+	lck       sync.Mutex
+	watchers  map[uint64]func(Change[K])
+	nextId    uint64
+	exclusion *exclusion
+}
+
+// NewMutableHandler initialises a new MutableHandler instance with the specified resolver and initial set of handlers.
+// The resolver can be nil if none of the keys to the handlers require resolution
+func NewMutableHandler[T, U, M any, K comparable](resolver *Resolver[M, K], handlers ...*Register[T, U, K]) *MutableHandler[T, U, M, K] {
+
+	mh := &MutableHandler[T, U, M, K]{
+		watchers:  map[uint64]func(Change[K]){},
+		exclusion: &exclusion{},
+	}
+
+	r := &routes[T, U, K]{handlers: map[K]types.Handler[T, U]{}, meta: map[K]bool{}}
+	for _, v := range handlers {
+		if v.Handler != nil {
+			r.set(v, mh.exclusion)
+		}
+	}
+	mh.routes.Store(r)
+
+	mh.Handler = func(ctx context.Context, r *types.Request[T, M, K]) (*U, error) {
+		rs := mh.routes.Load()
+		if len(rs.meta) > 0 {
+			ctx = withMeta(ctx, &r.Meta)
+		}
+
+		h, ok := rs.handlers[r.Key]
+		if ok {
+			return h(ctx, r.Data)
+		}
+
+		if resolver != nil && resolver.Resolve != nil {
+			h, ok := rs.handlers[resolver.Resolve(r.Key, &r.Meta)]
+			if ok {
+				return h(ctx, r.Data)
+			}
+		}
+
+		return nil, ErrHandlerNotFound
+	}
+
+	return mh
+}
+
+// update applies f to a copy of the current routes, and then publishes the copy.
+// Watchers are notified of the changes whilst the lock is held, so that they see changes in order
+func (mh *MutableHandler[T, U, M, K]) update(f func(r *routes[T, U, K]) ([]Change[K], error)) error {
+	mh.lck.Lock()
+	defer mh.lck.Unlock()
+
+	current := mh.routes.Load()
+	r := &routes[T, U, K]{handlers: maps.Clone(current.handlers), meta: maps.Clone(current.meta)}
+
+	changes, err := f(r)
+	if err != nil {
+		return err
+	}
+
+	mh.routes.Store(r)
+
+	for _, c := range changes {
+		for _, w := range mh.watchers {
+			w(c)
+		}
+	}
+	return nil
+}
+
+// Register adds the Handlers to the MutableHandler.  No Handlers are added if any
+// Key already has a Handler, or if any Handler is nil or has an invalid Rollout
+func (mh *MutableHandler[T, U, M, K]) Register(handlers ...*Register[T, U, K]) error {
+	return mh.update(func(r *routes[T, U, K]) ([]Change[K], error) {
+		var changes []Change[K]
+		for _, v := range handlers {
+			if err := v.validate(); err != nil {
+				return nil, err
+			}
+			if _, ok := r.handlers[v.Key]; ok {
+				return nil, fmt.Errorf("%w: %v", ErrDuplicateKey, v.Key)
+			}
+			r.set(v, mh.exclusion)
+			changes = append(changes, Change[K]{Kind: Registered, Key: v.Key})
+		}
+		return changes, nil
+	})
+}
+
+// Unregister removes the Handlers for the specified Keys, returning true if any were removed
+func (mh *MutableHandler[T, U, M, K]) Unregister(keys ...K) bool {
+	removed := false
+	mh.update(func(r *routes[T, U, K]) ([]Change[K], error) {
+		var changes []Change[K]
+		for _, k := range keys {
+			if _, ok := r.handlers[k]; ok {
+				r.remove(k)
+				changes = append(changes, Change[K]{Kind: Unregistered, Key: k})
+			}
+		}
+		removed = len(changes) > 0
+		return changes, nil
+	})
+	return removed
+}
+
+// Replace swaps the Handler for the Key of the Register, leaving the Handlers of other Keys unchanged.
+// Returns ErrHandlerNotFound if the Key has no Handler, or an error if the Handler is nil or has an
+// invalid Rollout, with no change made
+func (mh *MutableHandler[T, U, M, K]) Replace(handler *Register[T, U, K]) error {
+	return mh.update(func(r *routes[T, U, K]) ([]Change[K], error) {
+		if err := handler.validate(); err != nil {
+			return nil, err
+		}
+		if _, ok := r.handlers[handler.Key]; !ok {
+			return nil, fmt.Errorf("%w: %v", ErrHandlerNotFound, handler.Key)
+		}
+		r.set(handler, mh.exclusion)
+		return []Change[K]{{Kind: Replaced, Key: handler.Key}}, nil
+	})
+}
+
+// ReplaceAll atomically swaps the complete set of Handlers, so that each Request sees either the
+// previous set or the new set, but never a mixture.  Keys no longer present are reported as
+// Unregistered, and each listed Key as Registered or Replaced.  Returns an error (with no change
+// made) if any Key is duplicated or any Handler is nil or has an invalid Rollout
+func (mh *MutableHandler[T, U, M, K]) ReplaceAll(handlers ...*Register[T, U, K]) error {
+	return mh.update(func(r *routes[T, U, K]) ([]Change[K], error) {
+		seen := map[K]bool{}
+		for _, v := range handlers {
+			if err := v.validate(); err != nil {
+				return nil, err
+			}
+			if seen[v.Key] {
+				return nil, fmt.Errorf("%w: %v", ErrDuplicateKey, v.Key)
+			}
+			seen[v.Key] = true
+		}
+
+		var changes []Change[K]
+		for k := range r.handlers {
+			if !seen[k] {
+				r.remove(k)
+				changes = append(changes, Change[K]{Kind: Unregistered, Key: k})
+			}
+		}
+		for _, v := range handlers {
+			kind := Registered
+			if _, ok := r.handlers[v.Key]; ok {
+				kind = Replaced
+			}
+			r.set(v, mh.exclusion)
+			changes = append(changes, Change[K]{Kind: kind, Key: v.Key})
+		}
+		return changes, nil
+	})
+}
+
+// Keys returns the Keys that currently have Handlers
+func (mh *MutableHandler[T, U, M, K]) Keys() []K {
+	m := mh.routes.Load().handlers
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// Watch registers f to be called for each change to the Handlers, returning a function
+// that stops the notifications.  f is called synchronously, in the order the changes are made,
+// and must not modify the MutableHandler
+func (mh *MutableHandler[T, U, M, K]) Watch(f func(Change[K])) func() {
+	mh.lck.Lock()
+	defer mh.lck.Unlock()
+
+	id := mh.nextId
+	mh.nextId++
+	mh.watchers[id] = f
+
+	return func() {
+		mh.lck.Lock()
+		defer mh.lck.Unlock()
+		delete(mh.watchers, id)
+	}
+}
@@ -2,19 +2,20 @@ package mux
 
 import (
 	"context"
-	"errors"
+	"time"
 
 	"github.com/gford1000-go/saferr/types"
 )
 
 // ErrHandlerNotFound is returned if the key cannot be found in the MuxHandler
-var ErrHandlerNotFound = errors.New("handler not found")
+var ErrHandlerNotFound = &types.Error{Code: types.CodeNotFound, Message: "handler not found"}
 
 // Resolver encapsulates a map of KeyResolver information, created by NewResolver
 // Calling Resolve will attempt to find a match, and if found the KeyResolver
 // is used to attempt to resolve the key using the additional data
 type Resolver[M any, K comparable] struct {
-	Resolve func(key K, m *M) K
+	Resolve   func(key K, m *M) K
+	resolvers []*KeyResolver[M, K]
 }
 
 // KeyResolver maps a partially completed Key (i.e. with parameters or missing values) to a resolution
@@ -26,6 +27,9 @@ type KeyResolver[M any, K comparable] struct {
 	Key K
 	// KeyResolver defines a function that can fully resolve Keys
 	KeyResolver func(key K, m *M) K
+	// Targets optionally declares the fully defined Keys that KeyResolver can return,
+	// allowing NewValidatedHandler to confirm that each has a Handler
+	Targets []K
 }
 
 // NewResolver creates a new instance of Resolver containing the specified KeyResolver details
@@ -41,6 +45,7 @@ func NewResolver[M any, K comparable](resolvers ...*KeyResolver[M, K]) *Resolver
 	}
 
 	return &Resolver[M, K]{
+		resolvers: append([]*KeyResolver[M, K](nil), resolvers...),
 		Resolve: func(key K, meta *M) K {
 			if r, ok := m[key]; !ok {
 				return key
@@ -53,7 +58,49 @@ func NewResolver[M any, K comparable](resolvers ...*KeyResolver[M, K]) *Resolver
 
 // Handler provides the Handler to present to Go() or Responder.ListenAndHandle()
 type Handler[T, U, M any, K comparable] struct {
-	Handler func(ctx context.Context, t *types.Request[T, M, K]) (*U, error)
+	Handler  func(ctx context.Context, t *types.Request[T, M, K]) (*U, error)
+	keys     []K
+	resolver *Resolver[M, K]
+	lookup   func(r *types.Request[T, M, K]) (types.Handler[T, U], K, bool)
+	notFound *NotFound[T, U, M, K]
+	routes   []RouteInfo[K]
+	meta     bool
+	mount    bool
+}
+
+// RouteInfo describes a Key known to a Handler
+type RouteInfo[K comparable] struct {
+	// Key is either a fully defined Key with a Handler, or a partially completed Key of a KeyResolver
+	Key K
+	// Resolved is true if the Key is resolved by a KeyResolver, rather than having its own Handler
+	Resolved bool
+	// Targets are the Keys declared by the KeyResolver as those it can resolve to
+	Targets []K
+	// Predicates are the names of the Predicates of a Conditional, which must all match the Meta
+	Predicates []string
+	// Priority is the Priority of a Conditional
+	Priority int
+}
+
+// Routes lists the Keys with Handlers, in the order they were registered, followed by the Keys of
+// the KeyResolvers.  This is intended for startup diagnostics
+func (h *Handler[T, U, M, K]) Routes() []RouteInfo[K] {
+	var routes []RouteInfo[K]
+	if h.routes != nil {
+		routes = append(routes, h.routes...)
+	} else {
+		for _, k := range h.keys {
+			routes = append(routes, RouteInfo[K]{Key: k})
+		}
+	}
+	if h.resolver != nil {
+		for _, r := range h.resolver.resolvers {
+			if r.KeyResolver != nil {
+				routes = append(routes, RouteInfo[K]{Key: r.Key, Resolved: true, Targets: r.Targets})
+			}
+		}
+	}
+	return routes
 }
 
 // Register associate the specifed Key to a Handler.
@@ -63,6 +110,22 @@ type Register[T, U any, K comparable] struct {
 	Key K
 	// Handler for Requests with this Key
 	Handler types.Handler[T, U]
+	// Timeout, if set, is the maximum duration of the Handler, after which the context passed to the
+	// Handler is cancelled, and ErrHandlerTimeout is returned once the Handler returns.  The Handler is
+	// not abandoned, so the route (and the Responder) is held until it returns, and it should stop
+	// promptly when its context is cancelled
+	Timeout time.Duration
+	// MaxConcurrent, if set, limits the number of concurrent executions of the Handler.  This only
+	// has an effect if the Responder has more than one worker (see saferr.WithWorkers)
+	MaxConcurrent int
+	// Middleware wraps the Handler, with the first Middleware being the outermost
+	Middleware []Middleware[T, U]
+	// ReadOnly routes may run concurrently with each other, whereas other routes run exclusively.
+	// This only has an effect if the Responder has more than one worker (see saferr.WithWorkers)
+	ReadOnly bool
+	// Rollout, if set, sends a share of the Requests to alternative Handlers, or a copy of each
+	// Request to a Shadow Handler, reporting how they differ from the Handler
+	Rollout *Rollout[T, U, K]
 }
 
 // NewHandler initialises a new Handler instance with the specified resolver and set of handlers
@@ -71,29 +134,31 @@ func NewHandler[T, U, M any, K comparable](resolver *Resolver[M, K], handlers ..
 
 	// Map is used inside a closure to enforce readonly behaviour after creation
 	m := map[K]types.Handler[T, U]{}
+	var keys []K
+	var meta bool
+	x := &exclusion{}
 
 	for _, v := range handlers {
 		if v.Handler != nil {
-			m[v.Key] = v.Handler
+			if _, ok := m[v.Key]; !ok {
+				keys = append(keys, v.Key)
+			}
+			m[v.Key] = v.handler(x)
+			meta = meta || v.needsMeta()
 		}
 	}
 
-	return &Handler[T, U, M, K]{
-
-		Handler: func(ctx context.Context, r *types.Request[T, M, K]) (*U, error) {
-			h, ok := m[r.Key]
-			if ok {
-				return h(ctx, r.Data)
-			}
-
-			if resolver != nil && resolver.Resolve != nil {
-				h, ok := m[resolver.Resolve(r.Key, &r.Meta)]
-				if ok {
-					return h(ctx, r.Data)
-				}
-			}
-
-			return nil, ErrHandlerNotFound
-		},
+	lookup := func(r *types.Request[T, M, K]) (types.Handler[T, U], K, bool) {
+		h, ok := m[r.Key]
+		if ok || resolver == nil || resolver.Resolve == nil {
+			return h, r.Key, ok
+		}
+		key := resolver.Resolve(r.Key, &r.Meta)
+		h, ok = m[key]
+		return h, key, ok
 	}
+
+	h := newHandler(keys, resolver, lookup, nil)
+	h.meta = meta
+	return h
 }
@@ -0,0 +1,160 @@
+package mux
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/gford1000-go/saferr/types"
+)
+
+// NotFound configures the behaviour of a Handler when there is no Handler for the Key of a Request
+type NotFound[T, U, M any, K comparable] struct {
+	// Hook, if set, is called for each unmatched Request, with the Key of the Request,
+	// the Key it was resolved to (which is the same Key if not resolved) and the Meta
+	Hook func(ctx context.Context, key, resolved K, meta *M)
+	// Fallback, if set, handles unmatched Requests instead of ErrHandlerNotFound being returned,
+	// for example to forward them to a legacy service
+	Fallback func(ctx context.Context, r *types.Request[T, M, K]) (*U, error)
+	// Suggestions, if greater than zero, is the maximum number of registered Keys, close to the Key
+	// of the Request, that are suggested in the ErrHandlerNotFound returned.  Ignored if Fallback is set
+	Suggestions int
+}
+
+// newHandler creates a Handler that uses lookup to find the Handler for each Request,
+// applying nf if none is found
+func newHandler[T, U, M any, K comparable](keys []K, resolver *Resolver[M, K], lookup func(r *types.Request[T, M, K]) (types.Handler[T, U], K, bool), nf *NotFound[T, U, M, K]) *Handler[T, U, M, K] {
+	h := &Handler[T, U, M, K]{
+		keys:     keys,
+		resolver: resolver,
+		lookup:   lookup,
+		notFound: nf,
+	}
+
+	h.Handler = func(ctx context.Context, r *types.Request[T, M, K]) (*U, error) {
+		if h.mount && ctx.Value(routePathKey{}) == nil {
+			ctx = context.WithValue(ctx, routePathKey{}, &routePath{})
+		}
+
+		f, key, ok := lookup(r)
+		if ok {
+			if h.meta {
+				ctx = withMeta(ctx, &r.Meta)
+			}
+			appendRoutePath(ctx, key)
+			return f(ctx, r.Data)
+		}
+		return h.handleNotFound(ctx, r, key)
+	}
+
+	return h
+}
+
+// WithNotFound returns a Handler with the same Handlers and resolver, which behaves as specified by
+// nf when there is no Handler for the Key of a Request
+func (h *Handler[T, U, M, K]) WithNotFound(nf *NotFound[T, U, M, K]) *Handler[T, U, M, K] {
+	if h.lookup != nil {
+		c := newHandler(h.keys, h.resolver, h.lookup, nf)
+		c.routes = h.routes
+		c.meta = h.meta
+		c.mount = h.mount
+		return c
+	}
+
+	// Handler was not created by this package, so rely on the error it returns
+	inner := h.Handler
+	c := &Handler[T, U, M, K]{keys: h.keys, resolver: h.resolver, notFound: nf, routes: h.routes}
+	c.Handler = func(ctx context.Context, r *types.Request[T, M, K]) (*U, error) {
+		u, err := inner(ctx, r)
+		if errors.Is(err, ErrHandlerNotFound) {
+			return c.handleNotFound(ctx, r, r.Key)
+		}
+		return u, err
+	}
+	return c
+}
+
+func (h *Handler[T, U, M, K]) handleNotFound(ctx context.Context, r *types.Request[T, M, K], resolved K) (*U, error) {
+	nf := h.notFound
+	if nf == nil {
+		return nil, ErrHandlerNotFound
+	}
+
+	if nf.Hook != nil {
+		nf.Hook(ctx, r.Key, resolved, &r.Meta)
+	}
+
+	if nf.Fallback != nil {
+		return nf.Fallback(ctx, r)
+	}
+
+	if nf.Suggestions > 0 {
+		if suggestions := suggest(h.keys, resolved, nf.Suggestions); len(suggestions) > 0 {
+			return nil, ErrHandlerNotFound.
+				WithDetail("suggestions", suggestions).
+				WithCause(fmt.Errorf("did you mean %v?", suggestions))
+		}
+	}
+
+	return nil, ErrHandlerNotFound
+}
+
+// suggest returns up to n of keys that are closest to key, ignoring any that are too
+// different to be a likely mistake
+func suggest[K comparable](keys []K, key K, n int) []K {
+	target := fmt.Sprint(key)
+
+	type candidate struct {
+		key      K
+		s        string
+		distance int
+	}
+
+	var candidates []candidate
+	for _, k := range keys {
+		s := fmt.Sprint(k)
+		d := distance(target, s)
+		if d <= max(2, len(s)/3) {
+			candidates = append(candidates, candidate{key: k, s: s, distance: d})
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		return candidates[i].s < candidates[j].s
+	})
+
+	var result []K
+	for i := 0; i < len(candidates) && i < n; i++ {
+		result = append(result, candidates[i].key)
+	}
+	return result
+}
+
+// distance returns the Levenshtein distance between a and b
+func distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(rb)]
+}
@@ -0,0 +1,285 @@
+package mux
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/gford1000-go/saferr/types"
+)
+
+// ErrInvalidPattern is returned if a pattern cannot be parsed
+var ErrInvalidPattern = &types.Error{Code: types.CodeInvalidArgument, Message: "invalid pattern"}
+
+// ErrConflictingPatterns is returned if two patterns match the same Keys, and neither is more specific
+var ErrConflictingPatterns = &types.Error{Code: types.CodeInvalidArgument, Message: "conflicting patterns"}
+
+type segmentKind int
+
+const (
+	literalSegment segmentKind = iota
+	paramSegment
+	wildcardSegment
+)
+
+type segment struct {
+	kind  segmentKind
+	value string // literal value, or parameter name
+}
+
+// pattern is a parsed Key pattern, where segments are separated by '/'.
+// A segment may be a literal, a {param} matching exactly one segment, or (as the final segment only)
+// a {rest...} wildcard matching zero or more segments.
+type pattern struct {
+	raw      string
+	segments []segment
+}
+
+func (p *pattern) hasWildcard() bool {
+	return len(p.segments) > 0 && p.segments[len(p.segments)-1].kind == wildcardSegment
+}
+
+// fixed returns the number of segments before any wildcard
+func (p *pattern) fixed() int {
+	if p.hasWildcard() {
+		return len(p.segments) - 1
+	}
+	return len(p.segments)
+}
+
+func parsePattern(s string) (*pattern, error) {
+	p := &pattern{raw: s}
+	names := map[string]bool{}
+
+	parts := strings.Split(s, "/")
+	for i, part := range parts {
+		if !strings.HasPrefix(part, "{") {
+			if strings.ContainsAny(part, "{}") {
+				return nil, fmt.Errorf("%w: %q: braces must enclose the whole segment", ErrInvalidPattern, s)
+			}
+			p.segments = append(p.segments, segment{kind: literalSegment, value: part})
+			continue
+		}
+
+		name, ok := strings.CutSuffix(part[1:], "}")
+		if !ok || strings.ContainsAny(name, "{}") {
+			return nil, fmt.Errorf("%w: %q: unbalanced braces", ErrInvalidPattern, s)
+		}
+
+		kind := paramSegment
+		if n, ok := strings.CutSuffix(name, "..."); ok {
+			if i != len(parts)-1 {
+				return nil, fmt.Errorf("%w: %q: %s must be the final segment", ErrInvalidPattern, s, part)
+			}
+			kind, name = wildcardSegment, n
+		}
+
+		if name == "" {
+			return nil, fmt.Errorf("%w: %q: missing parameter name", ErrInvalidPattern, s)
+		}
+		if names[name] {
+			return nil, fmt.Errorf("%w: %q: duplicate parameter name %q", ErrInvalidPattern, s, name)
+		}
+		names[name] = true
+
+		p.segments = append(p.segments, segment{kind: kind, value: name})
+	}
+
+	return p, nil
+}
+
+// match returns the parameters extracted from key, and whether key matches the pattern
+func (p *pattern) match(key string) (map[string]string, bool) {
+	parts := strings.Split(key, "/")
+
+	n := p.fixed()
+	if len(parts) < n || (!p.hasWildcard() && len(parts) != n) {
+		return nil, false
+	}
+
+	var params map[string]string
+	for i, seg := range p.segments[:n] {
+		switch seg.kind {
+		case literalSegment:
+			if parts[i] != seg.value {
+				return nil, false
+			}
+		case paramSegment:
+			if params == nil {
+				params = map[string]string{}
+			}
+			params[seg.value] = parts[i]
+		}
+	}
+
+	if p.hasWildcard() {
+		if params == nil {
+			params = map[string]string{}
+		}
+		params[p.segments[n].value] = strings.Join(parts[n:], "/")
+	}
+
+	return params, true
+}
+
+// coveredBy returns true if every Key matched by p is also matched by q
+func (p *pattern) coveredBy(q *pattern) bool {
+	if !q.hasWildcard() {
+		if p.hasWildcard() || len(p.segments) != len(q.segments) {
+			return false
+		}
+	} else if p.fixed() < q.fixed() {
+		return false
+	}
+
+	for i, qs := range q.segments[:q.fixed()] {
+		ps := p.segments[i]
+		switch qs.kind {
+		case literalSegment:
+			if ps.kind != literalSegment || ps.value != qs.value {
+				return false
+			}
+		case paramSegment:
+			if ps.kind == wildcardSegment {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// disjoint returns true if no Key can be matched by both p and q
+func (p *pattern) disjoint(q *pattern) bool {
+	pn, qn := p.fixed(), q.fixed()
+	switch {
+	case !p.hasWildcard() && !q.hasWildcard() && pn != qn:
+		return true
+	case !p.hasWildcard() && pn < qn:
+		return true
+	case !q.hasWildcard() && qn < pn:
+		return true
+	}
+
+	for i := range min(pn, qn) {
+		ps, qs := p.segments[i], q.segments[i]
+		if ps.kind == literalSegment && qs.kind == literalSegment && ps.value != qs.value {
+			return true
+		}
+	}
+	return false
+}
+
+// checkConflict returns an error if p and q can match the same Key without one being more specific
+func (p *pattern) checkConflict(q *pattern) error {
+	if p.disjoint(q) {
+		return nil
+	}
+	pq, qp := p.coveredBy(q), q.coveredBy(p)
+	switch {
+	case pq && qp:
+		return fmt.Errorf("%w: %q and %q match the same keys", ErrConflictingPatterns, p.raw, q.raw)
+	case !pq && !qp:
+		return fmt.Errorf("%w: %q and %q overlap, but neither is more specific", ErrConflictingPatterns, p.raw, q.raw)
+	}
+	return nil
+}
+
+type paramsKey struct{}
+
+// Params returns the parameters extracted from the Key by the matching pattern, or nil
+// if the Handler was not invoked via a pattern with parameters
+func Params(ctx context.Context) map[string]string {
+	params, _ := ctx.Value(paramsKey{}).(map[string]string)
+	return params
+}
+
+// Param returns the named parameter extracted from the Key by the matching pattern,
+// or an empty string if the parameter does not exist
+func Param(ctx context.Context, name string) string {
+	return Params(ctx)[name]
+}
+
+type patternRoute[T, U any] struct {
+	pattern *pattern
+	handler types.Handler[T, U]
+}
+
+// NewPatternHandler initialises a new Handler instance whose string Keys are patterns, matched
+// directly against the Request.Key.  Patterns are '/' separated, with segments that are either
+// literals, {param} to match a single segment, or a final {rest...} to match the remaining segments.
+// For example "/customerSegment/{segment}" matches "/customerSegment/premier".
+//
+// The extracted parameters are available to the Handler via Params(ctx) and Param(ctx, name).
+//
+// As with net/http.ServeMux, when more than one pattern matches a Key, the most specific pattern
+// is used, with a literal segment more specific than a {param}, which is more specific than {rest...}.
+// An error is returned if any pattern or Rollout is invalid, or if two patterns can match the same Key without one
+// being more specific than the other.
+func NewPatternHandler[T, U, M any](handlers ...*Register[T, U, string]) (*Handler[T, U, M, string], error) {
+
+	// Keys without parameters are matched directly, other patterns are checked in turn
+	exact := map[string]types.Handler[T, U]{}
+	var routes []*patternRoute[T, U]
+	var all []*pattern
+	var keys []string
+	var meta bool
+	x := &exclusion{}
+
+	for _, v := range handlers {
+		if v.Handler == nil {
+			continue
+		}
+		if err := v.validate(); err != nil {
+			return nil, err
+		}
+		meta = meta || v.needsMeta()
+
+		p, err := parsePattern(v.Key)
+		if err != nil {
+			return nil, err
+		}
+		for _, q := range all {
+			if err := p.checkConflict(q); err != nil {
+				return nil, err
+			}
+		}
+		all = append(all, p)
+		keys = append(keys, v.Key)
+
+		if strings.Contains(v.Key, "{") {
+			routes = append(routes, &patternRoute[T, U]{pattern: p, handler: v.handler(x)})
+		} else {
+			exact[v.Key] = v.handler(x)
+		}
+	}
+
+	lookup := func(r *types.Request[T, M, string]) (types.Handler[T, U], string, bool) {
+		if h, ok := exact[r.Key]; ok {
+			return h, r.Key, true
+		}
+
+		var best *patternRoute[T, U]
+		var bestParams map[string]string
+		for _, route := range routes {
+			params, ok := route.pattern.match(r.Key)
+			if !ok {
+				continue
+			}
+			if best == nil || route.pattern.coveredBy(best.pattern) {
+				best, bestParams = route, params
+			}
+		}
+
+		if best == nil {
+			return nil, r.Key, false
+		}
+
+		return func(ctx context.Context, t *T) (*U, error) {
+			return best.handler(context.WithValue(ctx, paramsKey{}, bestParams), t)
+		}, best.pattern.raw, true
+	}
+
+	h := newHandler[T, U, M](keys, nil, lookup, nil)
+	h.meta = meta
+	return h, nil
+}
@@ -0,0 +1,148 @@
+package mux
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/gford1000-go/saferr/types"
+)
+
+// ErrAmbiguousRoutes is returned if more than one Conditional for a Key matches a Request,
+// and neither has precedence over the other
+var ErrAmbiguousRoutes = &types.Error{Code: types.CodeFailedPrecondition, Message: "ambiguous routes"}
+
+// ErrNilPredicate is returned if a Predicate does not provide a Match function
+var ErrNilPredicate = &types.Error{Code: types.CodeInvalidArgument, Message: "nil predicate"}
+
+// Predicate is a declared condition over the Meta of a Request
+type Predicate[M any] struct {
+	// Name describes the condition, for example "tenant=acme", so that the routing table is inspectable
+	Name string
+	// Match returns true if the Meta satisfies the condition
+	Match func(m *M) bool
+}
+
+// Conditional associates a Handler with a Key, but it is only used for Requests whose Meta
+// matches all of its Predicates.  This allows several Handlers to share a Key.
+//
+// Where more than one Conditional for a Key matches, the one with the highest Priority is used,
+// and then the one with the most Predicates.  If this still does not decide, ErrAmbiguousRoutes is returned.
+type Conditional[T, U, M any, K comparable] struct {
+	Register[T, U, K]
+	// When lists the Predicates, all of which must match.  If empty, the Conditional
+	// matches any Request for the Key, and so acts as the default
+	When []Predicate[M]
+	// Priority orders the Conditionals for a Key, with higher values taking precedence
+	Priority int
+}
+
+func (c *Conditional[T, U, M, K]) matches(m *M) bool {
+	for _, p := range c.When {
+		if !p.Match(m) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *Conditional[T, U, M, K]) names() []string {
+	names := make([]string, len(c.When))
+	for i, p := range c.When {
+		names[i] = p.Name
+	}
+	sort.Strings(names)
+	return names
+}
+
+// precedes returns true if c should be used rather than o when both match
+func (c *Conditional[T, U, M, K]) precedes(o *Conditional[T, U, M, K]) bool {
+	if c.Priority != o.Priority {
+		return c.Priority > o.Priority
+	}
+	return len(c.When) > len(o.When)
+}
+
+type conditionalRoute[T, U, M any, K comparable] struct {
+	*Conditional[T, U, M, K]
+	handler types.Handler[T, U]
+}
+
+// NewConditionalHandler initialises a new Handler instance, whose Handlers are chosen by both the Key
+// and the Meta of each Request.  The resolver can be nil if none of the keys require resolution.
+// An error is returned if a Conditional has a nil Handler, an invalid Rollout or a nil Predicate, or
+// if two Conditionals for the same Key have the same Priority and the same Predicates.
+func NewConditionalHandler[T, U, M any, K comparable](resolver *Resolver[M, K], routes ...*Conditional[T, U, M, K]) (*Handler[T, U, M, K], error) {
+
+	m := map[K][]*conditionalRoute[T, U, M, K]{}
+	var keys []K
+	var info []RouteInfo[K]
+	var meta bool
+	x := &exclusion{}
+
+	for _, c := range routes {
+		if err := c.Register.validate(); err != nil {
+			return nil, err
+		}
+		for _, p := range c.When {
+			if p.Match == nil {
+				return nil, fmt.Errorf("%w: key %v: %q", ErrNilPredicate, c.Key, p.Name)
+			}
+		}
+
+		existing, ok := m[c.Key]
+		if !ok {
+			keys = append(keys, c.Key)
+		}
+		for _, e := range existing {
+			if e.Priority == c.Priority && slices.Equal(e.names(), c.names()) {
+				return nil, fmt.Errorf("%w: key %v has more than one route with priority %d and predicates %v", ErrAmbiguousRoutes, c.Key, c.Priority, c.names())
+			}
+		}
+
+		m[c.Key] = append(existing, &conditionalRoute[T, U, M, K]{Conditional: c, handler: c.Register.handler(x)})
+		meta = meta || c.needsMeta()
+		info = append(info, RouteInfo[K]{Key: c.Key, Predicates: c.names(), Priority: c.Priority})
+	}
+
+	for _, candidates := range m {
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return candidates[i].precedes(candidates[j].Conditional)
+		})
+	}
+
+	sel := func(key K, meta *M) (types.Handler[T, U], bool) {
+		candidates := m[key]
+		for i, c := range candidates {
+			if !c.matches(meta) {
+				continue
+			}
+			for _, o := range candidates[i+1:] {
+				if c.precedes(o.Conditional) {
+					break
+				}
+				if o.matches(meta) {
+					err := fmt.Errorf("%w: key %v matches both %v and %v", ErrAmbiguousRoutes, key, c.names(), o.names())
+					return func(context.Context, *T) (*U, error) { return nil, err }, true
+				}
+			}
+			return c.handler, true
+		}
+		return nil, false
+	}
+
+	lookup := func(r *types.Request[T, M, K]) (types.Handler[T, U], K, bool) {
+		if h, ok := sel(r.Key, &r.Meta); ok || resolver == nil || resolver.Resolve == nil {
+			return h, r.Key, ok
+		}
+		key := resolver.Resolve(r.Key, &r.Meta)
+		h, ok := sel(key, &r.Meta)
+		return h, key, ok
+	}
+
+	h := newHandler(keys, resolver, lookup, nil)
+	h.routes = info
+	h.meta = meta
+	return h, nil
+}
@@ -0,0 +1,418 @@
+package mux
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/gford1000-go/saferr/types"
+)
+
+// ErrInvalidRollout is returned if the Variants of a Rollout have a nil Handler, a negative
+// Weight, or a total Weight greater than 100
+var ErrInvalidRollout = &types.Error{Code: types.CodeInvalidArgument, Message: "invalid rollout"}
+
+const (
+	// PrimaryVariant is the name used in a Divergence for the Handler of the Register
+	PrimaryVariant = "primary"
+	// ShadowVariant is the name used in a Divergence for the Shadow of the Rollout
+	ShadowVariant = "shadow"
+)
+
+// Variant is an alternative Handler for the Key of a Register, such as a new version being rolled out
+type Variant[T, U any] struct {
+	// Name identifies the Variant in a Divergence
+	Name string
+	// Weight is the percentage of Requests that are sent to the Variant
+	Weight int
+	// Handler for the Requests sent to the Variant
+	Handler types.Handler[T, U]
+}
+
+// DivergenceKind describes how a Variant or Shadow has diverged from the Handler of the Register
+type DivergenceKind int
+
+const (
+	// ResponseMismatch indicates the Shadow returned a different response or error to the Handler for the same Request
+	ResponseMismatch DivergenceKind = iota
+	// ErrorRateDifference indicates that the error rate of a Variant or the Shadow differs from that of
+	// the Handler by more than the MaxErrorRateDelta of the Rollout
+	ErrorRateDifference
+)
+
+// String returns the name of the DivergenceKind
+func (d DivergenceKind) String() string {
+	switch d {
+	case ResponseMismatch:
+		return "response mismatch"
+	case ErrorRateDifference:
+		return "error rate difference"
+	}
+	return fmt.Sprintf("divergenceKind(%d)", int(d))
+}
+
+// Divergence is reported when a Variant or the Shadow behaves differently to the Handler of the Register
+type Divergence[U any, K comparable] struct {
+	Kind DivergenceKind
+	// Key of the Register
+	Key K
+	// Variant is the name of the Variant, or ShadowVariant
+	Variant string
+	// Expected and ExpectedErr are the result of the Handler, for a ResponseMismatch
+	Expected    *U
+	ExpectedErr error
+	// Actual and ActualErr are the result of the Shadow, for a ResponseMismatch
+	Actual    *U
+	ActualErr error
+	// ErrorRate of the Variant, and BaselineErrorRate of the Handler, for an ErrorRateDifference
+	ErrorRate         float64
+	BaselineErrorRate float64
+}
+
+// Rollout configures the gradual introduction of new Handlers for the Key of a Register, either by
+// sending them a share of the Requests, or by sending them a copy of each Request as a Shadow
+type Rollout[T, U any, K comparable] struct {
+	// Variants each receive their Weight as a percentage of the Requests, with the Handler
+	// of the Register receiving the remainder
+	Variants []Variant[T, U]
+	// Sticky, if set, returns a value for the Request such that Requests with the same value are
+	// always sent to the same Variant.  The Meta of the Request is available using Meta(ctx).
+	// If not set, Requests are assigned at random
+	Sticky func(ctx context.Context, t *T) string
+	// Shadow, if set, receives a copy of each Request sent to the Handler of the Register.  It runs
+	// in the background once the Handler has completed, and its result is compared and then discarded
+	Shadow types.Handler[T, U]
+	// MaxShadows is the most Shadows that may run at once.  Requests that arrive whilst this many are
+	// running are not shadowed.  Default: 100
+	MaxShadows int
+	// CopyRequest returns a copy of the Request for the Shadow, which must share no data that the Handler
+	// may modify.  Defaults to a copy made using reflection, in which exported fields are copied
+	// recursively and unexported fields are shared
+	CopyRequest func(t *T) *T
+	// CopyResponse returns a copy of the response of the Handler, which is compared with that of the
+	// Shadow after the response has been returned.  It must share no data that the caller may modify.
+	// Defaults to a copy made in the same way as CopyRequest
+	CopyResponse func(u *U) *U
+	// Equal compares the responses of the Handler and the Shadow.  Defaults to reflect.DeepEqual
+	Equal func(a, b *U) bool
+	// Window is the number of Requests over which error rates are compared.  If zero, error rates are not compared
+	Window int
+	// MaxErrorRateDelta is the largest difference in error rates, between the Handler and a Variant or
+	// the Shadow, that is not reported
+	MaxErrorRateDelta float64
+	// Report, if set, is called with each Divergence.  It may be called from a background goroutine
+	Report func(d Divergence[U, K])
+}
+
+func (ro *Rollout[T, U, K]) validate() error {
+	total := 0
+	for _, v := range ro.Variants {
+		if v.Handler == nil {
+			return fmt.Errorf("%w: variant %q has a nil handler", ErrInvalidRollout, v.Name)
+		}
+		if v.Weight < 0 {
+			return fmt.Errorf("%w: variant %q has a negative weight", ErrInvalidRollout, v.Name)
+		}
+		total += v.Weight
+	}
+	if total > 100 {
+		return fmt.Errorf("%w: variant weights total %d%%", ErrInvalidRollout, total)
+	}
+	return nil
+}
+
+// handler returns a Handler that sends each Request to either h or one of the Variants,
+// and to the Shadow if specified
+func (ro *Rollout[T, U, K]) handler(key K, h types.Handler[T, U]) types.Handler[T, U] {
+	variants := append([]Variant[T, U](nil), ro.Variants...)
+	stats := &rolloutStats[U, K]{key: key, window: ro.Window, maxDelta: ro.MaxErrorRateDelta, counts: map[string]*rolloutCount{}}
+
+	equal := ro.Equal
+	if equal == nil {
+		equal = func(a, b *U) bool { return reflect.DeepEqual(a, b) }
+	}
+
+	copyRequest := ro.CopyRequest
+	if copyRequest == nil {
+		copyRequest = deepCopy[T]
+	}
+	copyResponse := ro.CopyResponse
+	if copyResponse == nil {
+		copyResponse = deepCopy[U]
+	}
+
+	maxShadows := ro.MaxShadows
+	if maxShadows <= 0 {
+		maxShadows = 100
+	}
+	shadows := make(chan struct{}, maxShadows)
+
+	report := func(ds []Divergence[U, K]) {
+		if ro.Report != nil {
+			for _, d := range ds {
+				ro.Report(d)
+			}
+		}
+	}
+
+	record := func(name string, err error) {
+		if ro.Window > 0 {
+			report(stats.record(name, err))
+		}
+	}
+
+	return func(ctx context.Context, t *T) (*U, error) {
+		var bucket int
+		if ro.Sticky != nil {
+			f := fnv.New32a()
+			f.Write([]byte(ro.Sticky(ctx, t)))
+			bucket = int(f.Sum32() % 100)
+		} else {
+			bucket = rand.IntN(100)
+		}
+
+		total := 0
+		for _, v := range variants {
+			total += v.Weight
+			if bucket < total {
+				u, err := v.Handler(ctx, t)
+				record(v.Name, err)
+				return u, err
+			}
+		}
+
+		shadow := ro.Shadow != nil
+		if shadow {
+			select {
+			case shadows <- struct{}{}:
+			default:
+				shadow = false
+			}
+		}
+
+		if !shadow {
+			u, err := h(ctx, t)
+			record(PrimaryVariant, err)
+			return u, err
+		}
+
+		// The slot is released by the Shadow, unless h panics before it is started
+		started := false
+		defer func() {
+			if !started {
+				<-shadows
+			}
+		}()
+
+		// Copy taken before h is called, in case h modifies the Request
+		c := copyRequest(t)
+
+		u, err := h(ctx, t)
+		record(PrimaryVariant, err)
+
+		// Copy taken before u is returned, in case the caller modifies the response
+		expected := copyResponse(u)
+
+		started = true
+		go func(ctx context.Context) {
+			defer func() { <-shadows }()
+
+			su, serr := runShadow(ctx, ro.Shadow, c)
+			record(ShadowVariant, serr)
+			if !equal(expected, su) || !sameError(err, serr) {
+				report([]Divergence[U, K]{{
+					Kind:        ResponseMismatch,
+					Key:         key,
+					Variant:     ShadowVariant,
+					Expected:    expected,
+					ExpectedErr: err,
+					Actual:      su,
+					ActualErr:   serr,
+				}})
+			}
+		}(context.WithoutCancel(ctx))
+
+		return u, err
+	}
+}
+
+func runShadow[T, U any](ctx context.Context, h types.Handler[T, U], t *T) (u *U, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			u, err = nil, fmt.Errorf("%w: %v", ErrHandlerPanic, r)
+		}
+	}()
+	return h(ctx, t)
+}
+
+// deepCopy returns a copy of t in which pointers, slices, maps, interfaces and the exported fields of
+// structs are copied recursively.  Unexported fields, channels and functions are shared with t
+func deepCopy[T any](t *T) *T {
+	if t == nil {
+		return nil
+	}
+	c := reflect.New(reflect.TypeFor[T]())
+	c.Elem().Set(copyValue(reflect.ValueOf(t).Elem(), map[copyVisit]reflect.Value{}))
+	return c.Interface().(*T)
+}
+
+// copyVisit identifies a pointer already copied, so that shared and cyclic pointers are copied once
+type copyVisit struct {
+	p   uintptr
+	typ reflect.Type
+}
+
+func copyValue(v reflect.Value, seen map[copyVisit]reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+		visit := copyVisit{v.Pointer(), v.Type()}
+		if c, ok := seen[visit]; ok {
+			return c
+		}
+		c := reflect.New(v.Type().Elem())
+		seen[visit] = c
+		c.Elem().Set(copyValue(v.Elem(), seen))
+		return c
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type()).Elem()
+		c.Set(copyValue(v.Elem(), seen))
+		return c
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := range v.Len() {
+			c.Index(i).Set(copyValue(v.Index(i), seen))
+		}
+		return c
+	case reflect.Array:
+		c := reflect.New(v.Type()).Elem()
+		for i := range v.Len() {
+			c.Index(i).Set(copyValue(v.Index(i), seen))
+		}
+		return c
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for iter := v.MapRange(); iter.Next(); {
+			c.SetMapIndex(copyValue(iter.Key(), seen), copyValue(iter.Value(), seen))
+		}
+		return c
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		for i := range v.NumField() {
+			if c.Field(i).CanSet() {
+				c.Field(i).Set(copyValue(v.Field(i), seen))
+			}
+		}
+		return c
+	}
+	return v
+}
+
+func sameError(a, b error) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return errors.Is(a, b) || errors.Is(b, a) || a.Error() == b.Error()
+}
+
+type rolloutCount struct {
+	requests int
+	errors   int
+}
+
+// rolloutStats counts the errors of the Handler, each Variant and the Shadow, so that
+// their error rates can be compared
+type rolloutStats[U any, K comparable] struct {
+	key      K
+	window   int
+	maxDelta float64
+	lck      sync.Mutex
+	n        int
+	counts   map[string]*rolloutCount
+}
+
+// record counts the result of a Request, returning any error rate divergences once the Window is
+// complete.  Only Requests to the Handler or a Variant advance the Window
+func (s *rolloutStats[U, K]) record(name string, err error) []Divergence[U, K] {
+	s.lck.Lock()
+	defer s.lck.Unlock()
+
+	c, ok := s.counts[name]
+	if !ok {
+		c = &rolloutCount{}
+		s.counts[name] = c
+	}
+	c.requests++
+	if err != nil {
+		c.errors++
+	}
+
+	if name == ShadowVariant {
+		return nil
+	}
+	s.n++
+	if s.n < s.window {
+		return nil
+	}
+
+	var ds []Divergence[U, K]
+	if base, ok := s.counts[PrimaryVariant]; ok {
+		baseline := float64(base.errors) / float64(base.requests)
+
+		names := make([]string, 0, len(s.counts))
+		for n := range s.counts {
+			if n != PrimaryVariant {
+				names = append(names, n)
+			}
+		}
+		sort.Strings(names)
+
+		for _, n := range names {
+			c := s.counts[n]
+			rate := float64(c.errors) / float64(c.requests)
+			if delta := rate - baseline; delta > s.maxDelta || -delta > s.maxDelta {
+				ds = append(ds, Divergence[U, K]{
+					Kind:              ErrorRateDifference,
+					Key:               s.key,
+					Variant:           n,
+					ErrorRate:         rate,
+					BaselineErrorRate: baseline,
+				})
+			}
+		}
+	}
+
+	s.n = 0
+	s.counts = map[string]*rolloutCount{}
+	return ds
+}
+
+type metaKey struct{}
+
+func withMeta[M any](ctx context.Context, m *M) context.Context {
+	return context.WithValue(ctx, metaKey{}, m)
+}
+
+// Meta returns the Meta of the Request being handled, or nil if it is not available.  The Meta is
+// only added to the context for Handlers that have a Register with a Rollout using Sticky
+func Meta[M any](ctx context.Context) *M {
+	m, _ := ctx.Value(metaKey{}).(*M)
+	return m
+}
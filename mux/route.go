@@ -0,0 +1,210 @@
+package mux
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gford1000-go/saferr/types"
+)
+
+// ErrHandlerTimeout is returned if a Handler does not complete within the Timeout of its Register
+var ErrHandlerTimeout = &types.Error{Code: types.CodeDeadlineExceeded, Message: "handler timed out", Retryable: true}
+
+// ErrHandlerPanic is returned if a Handler with a Timeout, or a Shadow, panics.  It is
+// saferr.ErrUncaughtHandlerPanic, and so matches it using errors.Is
+var ErrHandlerPanic = types.ErrHandlerPanic
+
+// Middleware wraps a Handler with additional behaviour
+type Middleware[T, U any] func(types.Handler[T, U]) types.Handler[T, U]
+
+// exclusion ensures that routes which are not ReadOnly run exclusively, whilst ReadOnly routes
+// may run concurrently with each other.  This only has an effect when the Responder has more
+// than one worker.  Unlike a sync.RWMutex, waiting for the lock ends if the context is cancelled,
+// so that a Request waits for the lock no longer than its Timeout
+type exclusion struct {
+	// exclusive is set once a route that is not ReadOnly has been added.  Until then no route
+	// needs the lock, and so ReadOnly routes only count themselves in unlocked
+	exclusive atomic.Bool
+	unlocked  atomic.Int64
+
+	lck     sync.Mutex
+	readers int
+	writer  bool
+	// waiting is the number of routes which are not ReadOnly waiting for the lock, which
+	// ReadOnly routes then wait behind, so that the others are not starved
+	waiting int
+	// changed is closed, and replaced, when the lock may have become available
+	changed chan struct{}
+}
+
+// addExclusive records that a route which is not ReadOnly is being added, waiting for the routes running
+// without the lock to complete, so that every route takes the lock from then on
+func (x *exclusion) addExclusive() {
+	if x.exclusive.Swap(true) {
+		return
+	}
+	for x.unlocked.Load() > 0 {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// lock waits until the route may run, returning the error of ctx if it is cancelled first
+func (x *exclusion) lock(ctx context.Context, exclusive bool) error {
+	x.lck.Lock()
+	if exclusive {
+		x.waiting++
+	}
+	for {
+		if exclusive && !x.writer && x.readers == 0 {
+			x.waiting--
+			x.writer = true
+			x.lck.Unlock()
+			return nil
+		}
+		if !exclusive && !x.writer && x.waiting == 0 {
+			x.readers++
+			x.lck.Unlock()
+			return nil
+		}
+
+		if x.changed == nil {
+			x.changed = make(chan struct{})
+		}
+		changed := x.changed
+		x.lck.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			if exclusive {
+				x.lck.Lock()
+				x.waiting--
+				x.broadcast()
+				x.lck.Unlock()
+			}
+			return ctx.Err()
+		}
+		x.lck.Lock()
+	}
+}
+
+func (x *exclusion) unlock(exclusive bool) {
+	x.lck.Lock()
+	defer x.lck.Unlock()
+
+	if exclusive {
+		x.writer = false
+	} else {
+		x.readers--
+	}
+	x.broadcast()
+}
+
+// broadcast wakes the routes waiting for the lock.  x.lck must be held
+func (x *exclusion) broadcast() {
+	if x.changed != nil {
+		close(x.changed)
+		x.changed = nil
+	}
+}
+
+// validate returns an error if the Register cannot be used
+func (r *Register[T, U, K]) validate() error {
+	if r.Handler == nil {
+		return fmt.Errorf("%w: key %v", ErrNilHandler, r.Key)
+	}
+	if r.Rollout != nil {
+		if err := r.Rollout.validate(); err != nil {
+			return fmt.Errorf("%w: key %v", err, r.Key)
+		}
+	}
+	return nil
+}
+
+// needsMeta returns true if the Meta of the Request must be added to the context passed to the Handler
+func (r *Register[T, U, K]) needsMeta() bool {
+	return r.Rollout != nil && r.Rollout.Sticky != nil
+}
+
+// handler returns the Handler of the Register, wrapped to enforce its per-route settings
+func (r *Register[T, U, K]) handler(x *exclusion) types.Handler[T, U] {
+	h := r.Handler
+
+	if r.Rollout != nil {
+		h = r.Rollout.handler(r.Key, h)
+	}
+
+	for i := len(r.Middleware) - 1; i >= 0; i-- {
+		h = r.Middleware[i](h)
+	}
+
+	inner := h
+	exclusive := !r.ReadOnly
+	if exclusive {
+		x.addExclusive()
+	}
+	h = func(ctx context.Context, t *T) (*U, error) {
+		if !exclusive && !x.exclusive.Load() {
+			// Checked again once counted, in case a route which is not ReadOnly has just been added
+			x.unlocked.Add(1)
+			if !x.exclusive.Load() {
+				defer x.unlocked.Add(-1)
+				return inner(ctx, t)
+			}
+			x.unlocked.Add(-1)
+		}
+
+		if err := x.lock(ctx, exclusive); err != nil {
+			return nil, err
+		}
+		defer x.unlock(exclusive)
+		return inner(ctx, t)
+	}
+
+	if r.MaxConcurrent > 0 {
+		sem := make(chan struct{}, r.MaxConcurrent)
+		limited := h
+		h = func(ctx context.Context, t *T) (*U, error) {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			defer func() { <-sem }()
+			return limited(ctx, t)
+		}
+	}
+
+	if r.Timeout > 0 {
+		h = withTimeout(h, r.Timeout)
+	}
+
+	return h
+}
+
+// withTimeout passes h a context that is cancelled after d, returning ErrHandlerTimeout if h has not
+// completed by then.  h is not abandoned, so the route (and the Responder) is held until h returns
+func withTimeout[T, U any](h types.Handler[T, U], d time.Duration) types.Handler[T, U] {
+	return func(parent context.Context, t *T) (u *U, err error) {
+		ctx, cancel := context.WithTimeout(parent, d)
+		defer cancel()
+
+		defer func() {
+			if rc := recover(); rc != nil {
+				u, err = nil, fmt.Errorf("%w: %v", ErrHandlerPanic, rc)
+			}
+		}()
+
+		u, err = h(ctx, t)
+		if ctx.Err() != nil {
+			if perr := parent.Err(); perr != nil {
+				return nil, perr
+			}
+			return nil, ErrHandlerTimeout
+		}
+		return u, err
+	}
+}
@@ -0,0 +1,147 @@
+package mux
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/gford1000-go/saferr/types"
+)
+
+// ErrInvalidService is returned if a service has no exported methods, or has exported methods
+// that cannot be registered as Handlers
+var ErrInvalidService = &types.Error{Code: types.CodeInvalidArgument, Message: "invalid service"}
+
+// MethodError describes an exported method of a service whose signature is not of the form
+// func(context.Context, *T) (*U, error), and so cannot be registered as a Handler
+type MethodError struct {
+	// Key the method would have been registered under
+	Key string
+	// Type of the method
+	Type reflect.Type
+}
+
+// Error describes the method
+func (e *MethodError) Error() string {
+	return fmt.Sprintf("%v: method %s has signature %v, want func(context.Context, *T) (*U, error)", ErrInvalidService, e.Key, e.Type)
+}
+
+// Unwrap returns ErrInvalidService
+func (e *MethodError) Unwrap() error {
+	return ErrInvalidService
+}
+
+var (
+	contextType = reflect.TypeFor[context.Context]()
+	errorType   = reflect.TypeFor[error]()
+)
+
+// RegisterService registers each exported method of svc as a Handler of the TypedHandler, with the
+// Key "Service.Method", where Service is the name of the concrete type of svc.  As with net/rpc, each
+// method must have the signature func(context.Context, *T) (*U, error), and be called using Call with
+// the RouteKey returned by NewRouteKey.
+//
+// Exported methods with a different signature are skipped, and reported as MethodErrors joined into
+// the returned error, whilst the other methods are still registered.  No methods are registered if
+// svc has no exported methods with the signature, or if any Key already has a Handler
+func RegisterService[M any](th *TypedHandler[M, string], svc any) error {
+	t := reflect.TypeOf(svc)
+	if t == nil {
+		return fmt.Errorf("%w: nil service", ErrInvalidService)
+	}
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return RegisterServiceName(th, t.Name(), svc)
+}
+
+// NewServiceHandler initialises a new TypedHandler with the methods of svc registered using RegisterService,
+// so that a service can be run using Go(ctx, h.Handler).  If only some methods were registered, the
+// TypedHandler is returned together with the MethodErrors of the methods that were skipped
+func NewServiceHandler[M any](svc any) (*TypedHandler[M, string], error) {
+	th := NewTypedHandler[M, string](nil)
+	if err := RegisterService(th, svc); err != nil {
+		if !skippedOnly(err) {
+			return nil, err
+		}
+		return th, err
+	}
+	return th, nil
+}
+
+// skippedOnly returns true if err only reports MethodErrors
+func skippedOnly(err error) bool {
+	j, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return false
+	}
+	for _, e := range j.Unwrap() {
+		if _, ok := e.(*MethodError); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// RegisterServiceName behaves as RegisterService, using name rather than the name of the type of svc
+func RegisterServiceName[M any](th *TypedHandler[M, string], name string, svc any) error {
+	v := reflect.ValueOf(svc)
+	if !v.IsValid() {
+		return fmt.Errorf("%w: nil service", ErrInvalidService)
+	}
+	if name == "" {
+		return fmt.Errorf("%w: service %v has no name", ErrInvalidService, v.Type())
+	}
+
+	handlers := map[string]types.Handler[any, any]{}
+	var errs []error
+
+	for i := 0; i < v.NumMethod(); i++ {
+		key := name + "." + v.Type().Method(i).Name
+		method := v.Method(i)
+
+		mt := method.Type()
+		if mt.IsVariadic() || mt.NumIn() != 2 || mt.NumOut() != 2 ||
+			mt.In(0) != contextType || mt.In(1).Kind() != reflect.Pointer ||
+			mt.Out(0).Kind() != reflect.Pointer || mt.Out(1) != errorType {
+			errs = append(errs, &MethodError{Key: key, Type: mt})
+			continue
+		}
+
+		reg := &Register[any, any, string]{Key: key, Handler: serviceMethod(key, method)}
+		handlers[key] = reg.handler(th.exclusion)
+	}
+
+	if len(handlers) == 0 {
+		return errors.Join(append([]error{fmt.Errorf("%w: service %s has no exported methods that can be registered", ErrInvalidService, name)}, errs...)...)
+	}
+	if err := th.add(handlers, false); err != nil {
+		return err
+	}
+	return errors.Join(errs...)
+}
+
+// serviceMethod adapts the method to a Handler of a TypedHandler
+func serviceMethod(key string, method reflect.Value) types.Handler[any, any] {
+	in := method.Type().In(1)
+
+	return func(ctx context.Context, data *any) (*any, error) {
+		arg := reflect.Zero(in)
+		if data != nil && *data != nil {
+			arg = reflect.ValueOf(*data)
+			if arg.Type() != in {
+				return nil, &TypeMismatchError{Key: key, Want: in, Got: arg.Type()}
+			}
+		}
+
+		out := method.Call([]reflect.Value{reflect.ValueOf(ctx), arg})
+
+		err, _ := out[1].Interface().(error)
+		if out[0].IsNil() {
+			return nil, err
+		}
+		u := out[0].Interface()
+		return &u, err
+	}
+}
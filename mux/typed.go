@@ -0,0 +1,191 @@
+package mux
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"reflect"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gford1000-go/saferr/types"
+)
+
+// ErrTypeMismatch is matched (using errors.Is) by a TypeMismatchError
+var ErrTypeMismatch = &types.Error{Code: types.CodeInvalidArgument, Message: "type mismatch"}
+
+// TypeMismatchError is returned when the request or response type used with Call does not
+// match the types of the Handler registered for the Key with Route
+type TypeMismatchError struct {
+	// Key of the Request
+	Key any
+	// Response is true if the mismatch is in the response type, otherwise it is in the request type
+	Response bool
+	// Want is the type expected
+	Want reflect.Type
+	// Got is the type provided
+	Got reflect.Type
+}
+
+// Error describes the mismatch
+func (e *TypeMismatchError) Error() string {
+	kind := "request"
+	if e.Response {
+		kind = "response"
+	}
+	return fmt.Sprintf("%v: %s for key %v: want %v, got %v", ErrTypeMismatch, kind, e.Key, e.Want, e.Got)
+}
+
+// Unwrap returns ErrTypeMismatch
+func (e *TypeMismatchError) Unwrap() error {
+	return ErrTypeMismatch
+}
+
+// TypedRequest is the Request type handled by a TypedHandler, with the request data held as any
+type TypedRequest[M any, K comparable] = types.Request[any, M, K]
+
+// RouteKey is the Key of a Route, together with the request and response types of its Handler,
+// so that Call can only be used with the types of the Route
+type RouteKey[T, U any, K comparable] struct {
+	key K
+}
+
+// NewRouteKey returns the RouteKey for key, for callers that do not have the RouteKey returned by Route,
+// such as those calling a Handler added by RegisterService.  A TypeMismatchError is returned by Call
+// if T or U differ from the types of the Handler registered for key
+func NewRouteKey[T, U any, K comparable](key K) RouteKey[T, U, K] {
+	return RouteKey[T, U, K]{key: key}
+}
+
+// Key returns the Key of the Route
+func (k RouteKey[T, U, K]) Key() K {
+	return k.key
+}
+
+// TypedHandler is a Handler for Routes with different request and response types for each Key.
+// Handlers are added using Route, and called using Call, which check the types on each side
+type TypedHandler[M any, K comparable] struct {
+	// Handler to present to Go() or Responder.ListenAndHandle()
+	Handler   func(ctx context.Context, r *TypedRequest[M, K]) (*any, error)
+	handlers  atomic.Pointer[map[K]types.Handler[any, any]]
+	lck       sync.Mutex
+	exclusion *exclusion
+	meta      atomic.Bool
+}
+
+// NewTypedHandler initialises a new TypedHandler with the specified resolver.
+// The resolver can be nil if none of the keys to the handlers require resolution
+func NewTypedHandler[M any, K comparable](resolver *Resolver[M, K]) *TypedHandler[M, K] {
+	th := &TypedHandler[M, K]{
+		exclusion: &exclusion{},
+	}
+	th.handlers.Store(&map[K]types.Handler[any, any]{})
+
+	th.Handler = func(ctx context.Context, r *TypedRequest[M, K]) (*any, error) {
+		m := *th.handlers.Load()
+		if th.meta.Load() {
+			ctx = withMeta(ctx, &r.Meta)
+		}
+
+		h, ok := m[r.Key]
+		if ok {
+			return h(ctx, r.Data)
+		}
+
+		if resolver != nil && resolver.Resolve != nil {
+			h, ok := m[resolver.Resolve(r.Key, &r.Meta)]
+			if ok {
+				return h(ctx, r.Data)
+			}
+		}
+
+		return nil, ErrHandlerNotFound
+	}
+
+	return th
+}
+
+// Route adds the Handler h for Requests with the specified Key, adapting its request and response types.
+// The RouteKey returned is used with Call
+func Route[T, U, M any, K comparable](th *TypedHandler[M, K], key K, h types.Handler[T, U]) (RouteKey[T, U, K], error) {
+	return RouteRegister(th, &Register[T, U, K]{Key: key, Handler: h})
+}
+
+// RouteRegister adds the Handler of the Register, with its per-route settings, adapting its
+// request and response types.  The RouteKey returned is used with Call
+func RouteRegister[T, U, M any, K comparable](th *TypedHandler[M, K], reg *Register[T, U, K]) (RouteKey[T, U, K], error) {
+	if err := reg.validate(); err != nil {
+		return RouteKey[T, U, K]{}, err
+	}
+
+	h := reg.handler(th.exclusion)
+	key := reg.Key
+
+	adapted := func(ctx context.Context, data *any) (*any, error) {
+		var t *T
+		if data != nil && *data != nil {
+			var ok bool
+			if t, ok = (*data).(*T); !ok {
+				return nil, &TypeMismatchError{Key: key, Want: reflect.TypeFor[*T](), Got: reflect.TypeOf(*data)}
+			}
+		}
+
+		u, err := h(ctx, t)
+		if u == nil {
+			return nil, err
+		}
+		var v any = u
+		return &v, err
+	}
+
+	if err := th.add(map[K]types.Handler[any, any]{key: adapted}, reg.needsMeta()); err != nil {
+		return RouteKey[T, U, K]{}, err
+	}
+	return NewRouteKey[T, U](key), nil
+}
+
+// add publishes the handlers, unless any of their Keys already has a Handler
+func (th *TypedHandler[M, K]) add(handlers map[K]types.Handler[any, any], meta bool) error {
+	th.lck.Lock()
+	defer th.lck.Unlock()
+
+	m := *th.handlers.Load()
+	for key := range handlers {
+		if _, ok := m[key]; ok {
+			return fmt.Errorf("%w: %v", ErrDuplicateKey, key)
+		}
+	}
+
+	next := maps.Clone(m)
+	maps.Copy(next, handlers)
+	th.handlers.Store(&next)
+	if meta {
+		th.meta.Store(true)
+	}
+
+	return nil
+}
+
+// Call sends t to the Handler registered with Route for the Key of route, returning its typed response.
+// A TypeMismatchError is returned if the types do not match those of the Handler
+func Call[T, U, M any, K comparable](ctx context.Context, requestor types.Requestor[TypedRequest[M, K], any], route RouteKey[T, U, K], t *T) (*U, error) {
+	var meta M
+	return CallWithMeta(ctx, requestor, route, meta, t)
+}
+
+// CallWithMeta behaves as Call, also passing the Meta information used to resolve the Key
+func CallWithMeta[T, U, M any, K comparable](ctx context.Context, requestor types.Requestor[TypedRequest[M, K], any], route RouteKey[T, U, K], meta M, t *T) (*U, error) {
+	key := route.key
+	var data any = t
+
+	resp, err := requestor.Send(ctx, &TypedRequest[M, K]{Key: key, Meta: meta, Data: &data})
+	if resp == nil || *resp == nil {
+		return nil, err
+	}
+
+	u, ok := (*resp).(*U)
+	if !ok {
+		return nil, &TypeMismatchError{Key: key, Response: true, Want: reflect.TypeOf(*resp), Got: reflect.TypeFor[*U]()}
+	}
+	return u, err
+}
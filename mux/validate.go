@@ -0,0 +1,61 @@
+package mux
+
+import (
+	"fmt"
+
+	"github.com/gford1000-go/saferr/types"
+)
+
+// ErrNilResolver is returned if a KeyResolver does not provide a resolution function
+var ErrNilResolver = &types.Error{Code: types.CodeInvalidArgument, Message: "nil key resolver"}
+
+// ErrUnreachableTarget is returned if a KeyResolver declares a Target that has no Handler
+var ErrUnreachableTarget = &types.Error{Code: types.CodeFailedPrecondition, Message: "key resolver target has no handler"}
+
+// ErrShadowedResolver is returned if a KeyResolver has the same Key as a Handler, since the
+// Handler is always found first and so the KeyResolver would never be used
+var ErrShadowedResolver = &types.Error{Code: types.CodeInvalidArgument, Message: "key resolver is shadowed by a handler"}
+
+// NewValidatedHandler behaves as NewHandler, but returns an error rather than silently
+// ignoring or overwriting invalid registrations.  An error is returned if:
+//   - a Key is registered more than once, by either the handlers or the resolver
+//   - a Register has a nil Handler or an invalid Rollout, or a KeyResolver has a nil resolution function
+//   - a KeyResolver has the same Key as a Handler
+//   - a KeyResolver declares a Target for which there is no Handler
+func NewValidatedHandler[T, U, M any, K comparable](resolver *Resolver[M, K], handlers ...*Register[T, U, K]) (*Handler[T, U, M, K], error) {
+
+	m := map[K]bool{}
+	for _, v := range handlers {
+		if err := v.validate(); err != nil {
+			return nil, err
+		}
+		if m[v.Key] {
+			return nil, fmt.Errorf("%w: %v", ErrDuplicateKey, v.Key)
+		}
+		m[v.Key] = true
+	}
+
+	if resolver != nil {
+		seen := map[K]bool{}
+		for _, r := range resolver.resolvers {
+			if r.KeyResolver == nil {
+				return nil, fmt.Errorf("%w: key %v", ErrNilResolver, r.Key)
+			}
+			if seen[r.Key] {
+				return nil, fmt.Errorf("%w: resolver key %v", ErrDuplicateKey, r.Key)
+			}
+			seen[r.Key] = true
+
+			if m[r.Key] {
+				return nil, fmt.Errorf("%w: %v", ErrShadowedResolver, r.Key)
+			}
+			for _, target := range r.Targets {
+				if !m[target] {
+					return nil, fmt.Errorf("%w: %v resolves to %v", ErrUnreachableTarget, r.Key, target)
+				}
+			}
+		}
+	}
+
+	return NewHandler[T, U, M, K](resolver, handlers...), nil
+}
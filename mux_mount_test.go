@@ -0,0 +1,205 @@
+package saferr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/gford1000-go/saferr/mux"
+	"github.com/gford1000-go/saferr/types"
+)
+
+func ExampleNewMountHandler() {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	logged := func(h types.Handler[string, string]) types.Handler[string, string] {
+		return func(ctx context.Context, input *string) (*string, error) {
+			fmt.Println("route:", mux.RoutePath(ctx))
+			return h(ctx, input)
+		}
+	}
+
+	billing, _ := mux.NewPatternHandler[string, string, string](
+		&mux.Register[string, string, string]{
+			Key: "invoice/{id}",
+			Handler: func(ctx context.Context, input *string) (*string, error) {
+				result := "invoice " + mux.Param(ctx, "id")
+				return &result, nil
+			},
+			Middleware: []mux.Middleware[string, string]{logged},
+		})
+
+	accounts := mux.NewHandler[string, string, string](nil,
+		&mux.Register[string, string, string]{
+			Key: "open",
+			Handler: func(ctx context.Context, input *string) (*string, error) {
+				result := "opened " + *input
+				return &result, nil
+			},
+			Middleware: []mux.Middleware[string, string]{logged},
+		})
+
+	m, err := mux.NewMountHandler(
+		&mux.Mount[string, string, string]{Prefix: "billing/", Handler: billing},
+		&mux.Mount[string, string, string]{Prefix: "accounts/", Handler: accounts},
+	)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	requestor := Go(ctx, m.Handler)
+
+	for _, key := range []string{"billing/invoice/42", "accounts/open"} {
+		data := "acme"
+		resp, _ := requestor.Send(ctx, &types.Request[string, string, string]{Key: key, Data: &data})
+		fmt.Println(*resp)
+	}
+
+	// Output:
+	// route: billing/invoice/{id}
+	// invoice 42
+	// route: accounts/open
+	// opened acme
+}
+
+func TestNewMountHandler_Nested(t *testing.T) {
+
+	path := func(ctx context.Context, input *int) (*string, error) {
+		result := mux.RoutePath(ctx)
+		return &result, nil
+	}
+
+	resolver := mux.NewResolver(&mux.KeyResolver[string, string]{
+		Key:         "user/{version}",
+		KeyResolver: func(key string, m *string) string { return "user/" + *m },
+		Targets:     []string{"user/v2"},
+	})
+
+	users := mux.NewHandler(resolver,
+		&mux.Register[int, string, string]{Key: "user/v2", Handler: path},
+	)
+	admin := mux.NewHandler[int, string, string](nil,
+		&mux.Register[int, string, string]{Key: "reset", Handler: path},
+	)
+
+	api, err := mux.NewMountHandler(
+		&mux.Mount[int, string, string]{Prefix: "api/", Handler: users},
+		&mux.Mount[int, string, string]{Prefix: "api/admin/", Handler: admin},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	root, err := mux.NewMountHandler(
+		&mux.Mount[int, string, string]{Prefix: "v1/", Handler: api},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		key  string
+		meta string
+		want string
+		err  error
+	}{
+		{key: "v1/api/user/{version}", meta: "v2", want: "v1/api/user/v2"},
+		{key: "v1/api/admin/reset", want: "v1/api/admin/reset"},
+		{key: "v1/api/admin/unknown", err: mux.ErrHandlerNotFound},
+		{key: "v2/api/admin/reset", err: mux.ErrHandlerNotFound},
+	}
+
+	for i, test := range tests {
+		v := 0
+		resp, err := root.Handler(context.Background(), &types.Request[int, string, string]{Key: test.key, Meta: test.meta, Data: &v})
+		if test.err != nil {
+			if !errors.Is(err, test.err) {
+				t.Fatalf("%d: expected %v, got %v", i, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%d: unexpected error: %v", i, err)
+		}
+		if *resp != test.want {
+			t.Fatalf("%d: expected %s, got %s", i, test.want, *resp)
+		}
+	}
+
+	var routes []string
+	for _, r := range root.Routes() {
+		routes = append(routes, fmt.Sprint(r.Key, r.Targets))
+	}
+	want := fmt.Sprint([]string{"v1/api/user/v2[]", "v1/api/user/{version}[v1/api/user/v2]", "v1/api/admin/reset[]"})
+	if fmt.Sprint(routes) != want {
+		t.Fatalf("expected %v, got %v", want, routes)
+	}
+}
+
+func TestNewMountHandler_Invalid(t *testing.T) {
+
+	sub := mux.NewHandler[int, int, string, string](nil)
+
+	_, err := mux.NewMountHandler(
+		&mux.Mount[int, int, string]{Prefix: "a/", Handler: sub},
+		&mux.Mount[int, int, string]{Prefix: "a/", Handler: sub},
+	)
+	if !errors.Is(err, mux.ErrDuplicateKey) {
+		t.Fatalf("expected ErrDuplicateKey, got %v", err)
+	}
+
+	_, err = mux.NewMountHandler(&mux.Mount[int, int, string]{Prefix: "a/"})
+	if !errors.Is(err, mux.ErrNilHandler) {
+		t.Fatalf("expected ErrNilHandler, got %v", err)
+	}
+}
+
+func TestNewMountHandler_Middleware(t *testing.T) {
+
+	tag := func(s string) mux.Middleware[string, string] {
+		return func(h types.Handler[string, string]) types.Handler[string, string] {
+			return func(ctx context.Context, input *string) (*string, error) {
+				resp, err := h(ctx, input)
+				if resp != nil {
+					result := *resp + s
+					resp = &result
+				}
+				return resp, err
+			}
+		}
+	}
+
+	echo := func(ctx context.Context, input *string) (*string, error) { return input, nil }
+
+	billing := mux.NewHandler[string, string, string](nil,
+		&mux.Register[string, string, string]{Key: "a", Handler: echo},
+		&mux.Register[string, string, string]{Key: "b", Handler: echo, Middleware: []mux.Middleware[string, string]{tag("-route")}})
+	accounts := mux.NewHandler[string, string, string](nil,
+		&mux.Register[string, string, string]{Key: "a", Handler: echo})
+
+	m, err := mux.NewMountHandler(
+		&mux.Mount[string, string, string]{Prefix: "billing/", Handler: billing,
+			Middleware: []mux.Middleware[string, string]{tag("-outer"), tag("-inner")}},
+		&mux.Mount[string, string, string]{Prefix: "accounts/", Handler: accounts},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The Middleware of the Mount runs outside the Middleware of the route, and only under its Prefix
+	for key, want := range map[string]string{
+		"billing/a":  "x-inner-outer",
+		"billing/b":  "x-route-inner-outer",
+		"accounts/a": "x",
+	} {
+		data := "x"
+		resp, err := m.Handler(context.Background(), &types.Request[string, string, string]{Key: key, Data: &data})
+		if err != nil || *resp != want {
+			t.Fatalf("expected %s for %s, got %v, %v", want, key, resp, err)
+		}
+	}
+}
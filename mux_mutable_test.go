@@ -0,0 +1,231 @@
+package saferr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/gford1000-go/saferr/mux"
+	"github.com/gford1000-go/saferr/types"
+)
+
+func ExampleNewMutableHandler() {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	square := func(ctx context.Context, input *int) (*int, error) {
+		result := *input * *input
+		return &result, nil
+	}
+	cube := func(ctx context.Context, input *int) (*int, error) {
+		result := *input * *input * *input
+		return &result, nil
+	}
+
+	m := mux.NewMutableHandler[int, int, string](nil,
+		&mux.Register[int, int, string]{Key: "square", Handler: square})
+
+	stop := m.Watch(func(c mux.Change[string]) {
+		fmt.Println(c.Kind, c.Key)
+	})
+	defer stop()
+
+	requestor := Go(ctx, m.Handler)
+
+	send := func(key string) {
+		v := 3
+		if response, err := requestor.Send(ctx, &types.Request[int, string, string]{Key: key, Data: &v}); err != nil {
+			fmt.Println(err)
+		} else {
+			fmt.Println(*response)
+		}
+	}
+
+	send("cube")
+
+	// Routes can be added and removed whilst the Responder is running
+	m.Register(&mux.Register[int, int, string]{Key: "cube", Handler: cube})
+	send("cube")
+
+	m.Unregister("square")
+	send("square")
+
+	// Output:
+	// handler not found
+	// registered cube
+	// 27
+	// unregistered square
+	// handler not found
+}
+
+func TestMutableHandler_RegisterErrors(t *testing.T) {
+
+	h := func(ctx context.Context, input *int) (*int, error) { return input, nil }
+
+	m := mux.NewMutableHandler[int, int, string](nil,
+		&mux.Register[int, int, string]{Key: "a", Handler: h})
+
+	if err := m.Register(
+		&mux.Register[int, int, string]{Key: "b", Handler: h},
+		&mux.Register[int, int, string]{Key: "a", Handler: h}); !errors.Is(err, mux.ErrDuplicateKey) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Failed registrations make no changes
+	if keys := m.Keys(); len(keys) != 1 {
+		t.Fatalf("unexpected keys: %v", keys)
+	}
+
+	if err := m.Register(&mux.Register[int, int, string]{Key: "c"}); !errors.Is(err, mux.ErrNilHandler) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m.Unregister("z") {
+		t.Fatal("should not be able to unregister a missing key")
+	}
+}
+
+func TestMutableHandler_ReplaceAll(t *testing.T) {
+
+	constant := func(v int) types.Handler[int, int] {
+		return func(ctx context.Context, input *int) (*int, error) { return &v, nil }
+	}
+
+	m := mux.NewMutableHandler[int, int, string](nil,
+		&mux.Register[int, int, string]{Key: "a", Handler: constant(1)},
+		&mux.Register[int, int, string]{Key: "b", Handler: constant(1)})
+
+	var changes []mux.Change[string]
+	m.Watch(func(c mux.Change[string]) { changes = append(changes, c) })
+
+	// Concurrent lookups must see a complete set of handlers, either the old or the new
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				a, errA := m.Handler(context.Background(), &types.Request[int, string, string]{Key: "a"})
+				if errA != nil {
+					t.Errorf("unexpected error: %v", errA)
+					return
+				}
+				if *a != 1 && *a != 2 {
+					t.Errorf("unexpected value: %d", *a)
+					return
+				}
+			}
+		}()
+	}
+
+	if err := m.ReplaceAll(
+		&mux.Register[int, int, string]{Key: "a", Handler: constant(2)},
+		&mux.Register[int, int, string]{Key: "c", Handler: constant(2)}); err != nil {
+		t.Fatal(err)
+	}
+
+	close(stop)
+	wg.Wait()
+
+	if _, err := m.Handler(context.Background(), &types.Request[int, string, string]{Key: "b"}); !errors.Is(err, mux.ErrHandlerNotFound) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[mux.Change[string]]bool{
+		{Kind: mux.Unregistered, Key: "b"}: true,
+		{Kind: mux.Replaced, Key: "a"}:     true,
+		{Kind: mux.Registered, Key: "c"}:   true,
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("unexpected changes: %v", changes)
+	}
+	for _, c := range changes {
+		if !expected[c] {
+			t.Fatalf("unexpected change: %v", c)
+		}
+	}
+}
+
+func TestMutableHandler_ReplaceKey(t *testing.T) {
+
+	constant := func(v int) types.Handler[int, int] {
+		return func(ctx context.Context, input *int) (*int, error) { return &v, nil }
+	}
+
+	m := mux.NewMutableHandler[int, int, string](nil,
+		&mux.Register[int, int, string]{Key: "a", Handler: constant(1)},
+		&mux.Register[int, int, string]{Key: "b", Handler: constant(1)})
+
+	var changes []mux.Change[string]
+	m.Watch(func(c mux.Change[string]) { changes = append(changes, c) })
+
+	if err := m.Replace(&mux.Register[int, int, string]{Key: "a", Handler: constant(2)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Replace(&mux.Register[int, int, string]{Key: "c", Handler: constant(2)}); !errors.Is(err, mux.ErrHandlerNotFound) {
+		t.Fatalf("expected ErrHandlerNotFound, got %v", err)
+	}
+	if err := m.Replace(&mux.Register[int, int, string]{Key: "b"}); !errors.Is(err, mux.ErrNilHandler) {
+		t.Fatalf("expected ErrNilHandler, got %v", err)
+	}
+
+	for key, want := range map[string]int{"a": 2, "b": 1} {
+		resp, err := m.Handler(context.Background(), &types.Request[int, string, string]{Key: key})
+		if err != nil || *resp != want {
+			t.Fatalf("expected %d for %s, got %v, %v", want, key, resp, err)
+		}
+	}
+
+	// Only the replaced Key is reported
+	if fmt.Sprint(changes) != "[{replaced a}]" {
+		t.Fatalf("unexpected changes: %v", changes)
+	}
+}
+
+func TestMutableHandler_MetaReset(t *testing.T) {
+
+	// hasMeta reports whether the Meta of the Request was added to the context
+	hasMeta := func(ctx context.Context, input *int) (*int, error) {
+		v := 0
+		if mux.Meta[string](ctx) != nil {
+			v = 1
+		}
+		return &v, nil
+	}
+
+	m := mux.NewMutableHandler[int, int, string](nil,
+		&mux.Register[int, int, string]{Key: "plain", Handler: hasMeta},
+		&mux.Register[int, int, string]{
+			Key:     "sticky",
+			Handler: hasMeta,
+			Rollout: &mux.Rollout[int, int, string]{
+				Variants: []mux.Variant[int, int]{{Name: "v2", Weight: 5, Handler: hasMeta}},
+				Sticky:   func(ctx context.Context, t *int) string { return *mux.Meta[string](ctx) },
+			},
+		})
+
+	plain := func() int {
+		resp, err := m.Handler(context.Background(), &types.Request[int, string, string]{Key: "plain", Meta: "user"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return *resp
+	}
+
+	if plain() != 1 {
+		t.Fatal("expected the Meta to be added whilst a Sticky Rollout is registered")
+	}
+
+	m.Unregister("sticky")
+	if plain() != 0 {
+		t.Fatal("expected the Meta to no longer be added once the Sticky Rollout is removed")
+	}
+}
@@ -0,0 +1,102 @@
+package saferr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/gford1000-go/saferr/mux"
+	"github.com/gford1000-go/saferr/types"
+)
+
+func ExampleHandler_WithNotFound() {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	square := func(ctx context.Context, input *int) (*int, error) {
+		result := *input * *input
+		return &result, nil
+	}
+
+	m := mux.NewHandler[int, int, string](nil,
+		&mux.Register[int, int, string]{Key: "math/square", Handler: square},
+	).WithNotFound(&mux.NotFound[int, int, string, string]{
+		Hook: func(ctx context.Context, key, resolved string, meta *string) {
+			fmt.Println("not found:", key)
+		},
+		Suggestions: 3,
+	})
+
+	requestor := Go(ctx, m.Handler)
+
+	v := 4
+	_, err := requestor.Send(ctx, &types.Request[int, string, string]{Key: "math/sqaure", Data: &v})
+
+	fmt.Println(err)
+
+	// Output:
+	// not found: math/sqaure
+	// handler not found: did you mean [math/square]?
+}
+
+func TestHandler_WithNotFound_Fallback(t *testing.T) {
+
+	legacy := func(ctx context.Context, r *types.Request[int, string, string]) (*string, error) {
+		result := "legacy:" + r.Key
+		return &result, nil
+	}
+
+	resolver := mux.NewResolver(&mux.KeyResolver[string, string]{
+		Key:         "user/{version}",
+		KeyResolver: func(key string, m *string) string { return "user/" + *m },
+	})
+
+	var resolvedKeys []string
+	m := mux.NewHandler(resolver,
+		&mux.Register[int, string, string]{
+			Key: "user/v2",
+			Handler: func(ctx context.Context, input *int) (*string, error) {
+				result := "v2"
+				return &result, nil
+			},
+		},
+	).WithNotFound(&mux.NotFound[int, string, string, string]{
+		Hook: func(ctx context.Context, key, resolved string, meta *string) {
+			resolvedKeys = append(resolvedKeys, resolved)
+		},
+		Fallback: legacy,
+	})
+
+	for meta, expected := range map[string]string{"v2": "v2", "v1": "legacy:user/{version}"} {
+		response, err := m.Handler(context.Background(), &types.Request[int, string, string]{Key: "user/{version}", Meta: meta})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *response != expected {
+			t.Fatalf("expected %s, got %s", expected, *response)
+		}
+	}
+
+	if len(resolvedKeys) != 1 || resolvedKeys[0] != "user/v1" {
+		t.Fatalf("hook should receive the resolved key: %v", resolvedKeys)
+	}
+}
+
+func TestHandler_WithNotFound_NoSuggestions(t *testing.T) {
+
+	h := func(ctx context.Context, input *int) (*int, error) { return input, nil }
+
+	m := mux.NewHandler[int, int, string](nil,
+		&mux.Register[int, int, string]{Key: "math/square", Handler: h},
+	).WithNotFound(&mux.NotFound[int, int, string, string]{Suggestions: 3})
+
+	_, err := m.Handler(context.Background(), &types.Request[int, string, string]{Key: "text/upper"})
+	if !errors.Is(err, mux.ErrHandlerNotFound) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err.Error() != mux.ErrHandlerNotFound.Error() {
+		t.Fatalf("unrelated keys should not be suggested: %v", err)
+	}
+}
@@ -0,0 +1,132 @@
+package saferr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/gford1000-go/saferr/mux"
+	"github.com/gford1000-go/saferr/types"
+)
+
+func ExampleNewPatternHandler() {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	describe := func(name string) types.Handler[int, string] {
+		return func(ctx context.Context, input *int) (*string, error) {
+			result := fmt.Sprintf("%s %v", name, mux.Params(ctx))
+			return &result, nil
+		}
+	}
+
+	m, err := mux.NewPatternHandler[int, string, string](
+		&mux.Register[int, string, string]{
+			Key:     "/customerSegment/{segment}",
+			Handler: describe("segment"),
+		}, &mux.Register[int, string, string]{
+			Key:     "/customerSegment/premier",
+			Handler: describe("premier"),
+		}, &mux.Register[int, string, string]{
+			Key:     "/files/{path...}",
+			Handler: describe("files"),
+		})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	requestor := Go(ctx, m.Handler)
+
+	v := 1
+	for _, key := range []string{"/customerSegment/standard", "/customerSegment/premier", "/files/a/b/c", "/other"} {
+		if response, err := requestor.Send(ctx, &types.Request[int, string, string]{Key: key, Data: &v}); err != nil {
+			fmt.Println(err)
+		} else {
+			fmt.Println(*response)
+		}
+	}
+
+	// Output:
+	// segment map[segment:standard]
+	// premier map[]
+	// files map[path:a/b/c]
+	// handler not found
+}
+
+func TestNewPatternHandler_Precedence(t *testing.T) {
+
+	named := func(name string) types.Handler[int, string] {
+		return func(ctx context.Context, input *int) (*string, error) {
+			return &name, nil
+		}
+	}
+
+	m, err := mux.NewPatternHandler[int, string, string](
+		&mux.Register[int, string, string]{Key: "a/{rest...}", Handler: named("a/{rest...}")},
+		&mux.Register[int, string, string]{Key: "a/{x}", Handler: named("a/{x}")},
+		&mux.Register[int, string, string]{Key: "a/{x}/c/d", Handler: named("a/{x}/c/d")},
+		&mux.Register[int, string, string]{Key: "a/b/{y}", Handler: named("a/b/{y}")},
+		&mux.Register[int, string, string]{Key: "a/b/c", Handler: named("a/b/c")},
+		&mux.Register[int, string, string]{Key: "{rest...}", Handler: named("{rest...}")},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]string{
+		"a":         "a/{rest...}",
+		"a/b":       "a/{x}",
+		"a/z/c/d":   "a/{x}/c/d",
+		"a/b/d":     "a/b/{y}",
+		"a/b/c":     "a/b/c",
+		"a/b/c/d/e": "a/{rest...}",
+		"z":         "{rest...}",
+	}
+
+	for key, expected := range tests {
+		response, err := m.Handler(context.Background(), &types.Request[int, string, string]{Key: key})
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", key, err)
+		}
+		if *response != expected {
+			t.Fatalf("%s: expected %s, got %s", key, expected, *response)
+		}
+	}
+}
+
+func TestNewPatternHandler_Errors(t *testing.T) {
+
+	h := func(ctx context.Context, input *int) (*int, error) { return input, nil }
+
+	tests := []struct {
+		patterns []string
+		err      error
+	}{
+		{[]string{"a/{x"}, mux.ErrInvalidPattern},
+		{[]string{"a/b{x}"}, mux.ErrInvalidPattern},
+		{[]string{"a/{}"}, mux.ErrInvalidPattern},
+		{[]string{"a/{x...}/b"}, mux.ErrInvalidPattern},
+		{[]string{"a/{x}/{x}"}, mux.ErrInvalidPattern},
+		{[]string{"a/{x}", "a/{y}"}, mux.ErrConflictingPatterns},
+		{[]string{"a/{x}", "{y}/b"}, mux.ErrConflictingPatterns},
+		{[]string{"a/{x}", "{y}/b/c"}, nil},
+	}
+
+	for i, test := range tests {
+		var registers []*mux.Register[int, int, string]
+		for _, p := range test.patterns {
+			registers = append(registers, &mux.Register[int, int, string]{Key: p, Handler: h})
+		}
+
+		_, err := mux.NewPatternHandler[int, int, string](registers...)
+		if test.err == nil && err != nil {
+			t.Fatalf("%d: unexpected error: %v", i, err)
+		}
+		if !errors.Is(err, test.err) {
+			t.Fatalf("%d: expected %v, got %v", i, test.err, err)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package saferr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/gford1000-go/saferr/mux"
+	"github.com/gford1000-go/saferr/types"
+)
+
+type tenantMeta struct {
+	Tenant  string
+	Version int
+	Beta    bool
+}
+
+func ExampleNewConditionalHandler() {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	reply := func(s string) func(ctx context.Context, input *int) (*string, error) {
+		return func(ctx context.Context, input *int) (*string, error) {
+			result := fmt.Sprintf("%s:%d", s, *input)
+			return &result, nil
+		}
+	}
+
+	isAcme := mux.Predicate[tenantMeta]{Name: "tenant=acme", Match: func(m *tenantMeta) bool { return m.Tenant == "acme" }}
+	isBeta := mux.Predicate[tenantMeta]{Name: "beta", Match: func(m *tenantMeta) bool { return m.Beta }}
+
+	m, err := mux.NewConditionalHandler[int, string](nil,
+		&mux.Conditional[int, string, tenantMeta, string]{
+			Register: mux.Register[int, string, string]{Key: "order", Handler: reply("default")},
+		},
+		&mux.Conditional[int, string, tenantMeta, string]{
+			Register: mux.Register[int, string, string]{Key: "order", Handler: reply("acme")},
+			When:     []mux.Predicate[tenantMeta]{isAcme},
+		},
+		&mux.Conditional[int, string, tenantMeta, string]{
+			Register: mux.Register[int, string, string]{Key: "order", Handler: reply("acme-beta")},
+			When:     []mux.Predicate[tenantMeta]{isAcme, isBeta},
+		},
+	)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	for _, r := range m.Routes() {
+		fmt.Println(r.Key, r.Predicates)
+	}
+
+	requestor := Go(ctx, m.Handler)
+
+	for _, meta := range []tenantMeta{{Tenant: "other"}, {Tenant: "acme"}, {Tenant: "acme", Beta: true}} {
+		v := 1
+		resp, _ := requestor.Send(ctx, &types.Request[int, tenantMeta, string]{Key: "order", Meta: meta, Data: &v})
+		fmt.Println(*resp)
+	}
+
+	// Output:
+	// order []
+	// order [tenant=acme]
+	// order [beta tenant=acme]
+	// default:1
+	// acme:1
+	// acme-beta:1
+}
+
+func TestNewConditionalHandler_Priority(t *testing.T) {
+
+	reply := func(s string) types.Handler[int, string] {
+		return func(ctx context.Context, input *int) (*string, error) { return &s, nil }
+	}
+
+	v1 := mux.Predicate[tenantMeta]{Name: "version=1", Match: func(m *tenantMeta) bool { return m.Version == 1 }}
+	beta := mux.Predicate[tenantMeta]{Name: "beta", Match: func(m *tenantMeta) bool { return m.Beta }}
+
+	m, err := mux.NewConditionalHandler[int, string](nil,
+		&mux.Conditional[int, string, tenantMeta, string]{
+			Register: mux.Register[int, string, string]{Key: "k", Handler: reply("v1")},
+			When:     []mux.Predicate[tenantMeta]{v1},
+		},
+		&mux.Conditional[int, string, tenantMeta, string]{
+			Register: mux.Register[int, string, string]{Key: "k", Handler: reply("beta")},
+			When:     []mux.Predicate[tenantMeta]{beta},
+			Priority: 10,
+		},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		meta tenantMeta
+		want string
+		err  error
+	}{
+		{meta: tenantMeta{Version: 1}, want: "v1"},
+		{meta: tenantMeta{Beta: true}, want: "beta"},
+		{meta: tenantMeta{Version: 1, Beta: true}, want: "beta"},
+		{meta: tenantMeta{Version: 2}, err: mux.ErrHandlerNotFound},
+	}
+
+	for i, test := range tests {
+		v := 0
+		resp, err := m.Handler(context.Background(), &types.Request[int, tenantMeta, string]{Key: "k", Meta: test.meta, Data: &v})
+		if test.err != nil {
+			if !errors.Is(err, test.err) {
+				t.Fatalf("%d: expected %v, got %v", i, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%d: unexpected error: %v", i, err)
+		}
+		if *resp != test.want {
+			t.Fatalf("%d: expected %s, got %s", i, test.want, *resp)
+		}
+	}
+}
+
+func TestNewConditionalHandler_Ambiguous(t *testing.T) {
+
+	reply := func(s string) types.Handler[int, string] {
+		return func(ctx context.Context, input *int) (*string, error) { return &s, nil }
+	}
+
+	v1 := mux.Predicate[tenantMeta]{Name: "version=1", Match: func(m *tenantMeta) bool { return m.Version == 1 }}
+	beta := mux.Predicate[tenantMeta]{Name: "beta", Match: func(m *tenantMeta) bool { return m.Beta }}
+
+	m, err := mux.NewConditionalHandler[int, string](nil,
+		&mux.Conditional[int, string, tenantMeta, string]{
+			Register: mux.Register[int, string, string]{Key: "k", Handler: reply("v1")},
+			When:     []mux.Predicate[tenantMeta]{v1},
+		},
+		&mux.Conditional[int, string, tenantMeta, string]{
+			Register: mux.Register[int, string, string]{Key: "k", Handler: reply("beta")},
+			When:     []mux.Predicate[tenantMeta]{beta},
+		},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	v := 0
+	_, err = m.Handler(context.Background(), &types.Request[int, tenantMeta, string]{Key: "k", Meta: tenantMeta{Version: 1, Beta: true}, Data: &v})
+	if !errors.Is(err, mux.ErrAmbiguousRoutes) {
+		t.Fatalf("expected ErrAmbiguousRoutes, got %v", err)
+	}
+
+	resp, err := m.Handler(context.Background(), &types.Request[int, tenantMeta, string]{Key: "k", Meta: tenantMeta{Beta: true}, Data: &v})
+	if err != nil || *resp != "beta" {
+		t.Fatalf("unexpected response: %v, %v", resp, err)
+	}
+
+	_, err = mux.NewConditionalHandler[int, string](nil,
+		&mux.Conditional[int, string, tenantMeta, string]{
+			Register: mux.Register[int, string, string]{Key: "k", Handler: reply("a")},
+			When:     []mux.Predicate[tenantMeta]{v1},
+		},
+		&mux.Conditional[int, string, tenantMeta, string]{
+			Register: mux.Register[int, string, string]{Key: "k", Handler: reply("b")},
+			When:     []mux.Predicate[tenantMeta]{v1},
+		},
+	)
+	if !errors.Is(err, mux.ErrAmbiguousRoutes) {
+		t.Fatalf("expected ErrAmbiguousRoutes for duplicate routes, got %v", err)
+	}
+
+	_, err = mux.NewConditionalHandler[int, string](nil,
+		&mux.Conditional[int, string, tenantMeta, string]{
+			Register: mux.Register[int, string, string]{Key: "k", Handler: reply("a")},
+			When:     []mux.Predicate[tenantMeta]{{Name: "broken"}},
+		},
+	)
+	if !errors.Is(err, mux.ErrNilPredicate) {
+		t.Fatalf("expected ErrNilPredicate, got %v", err)
+	}
+}
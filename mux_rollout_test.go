@@ -0,0 +1,245 @@
+package saferr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gford1000-go/saferr/mux"
+	"github.com/gford1000-go/saferr/types"
+)
+
+func ExampleRollout() {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	square := func(ctx context.Context, input *int) (*int, error) {
+		result := *input * *input
+		return &result, nil
+	}
+
+	// The rewrite has a bug for negative numbers
+	rewrite := func(ctx context.Context, input *int) (*int, error) {
+		result := *input * max(*input, 0)
+		return &result, nil
+	}
+
+	reports := make(chan mux.Divergence[int, string], 1)
+
+	m := mux.NewHandler[int, int, string](nil,
+		&mux.Register[int, int, string]{
+			Key:     "math/square",
+			Handler: square,
+			Rollout: &mux.Rollout[int, int, string]{
+				Shadow: rewrite,
+				Report: func(d mux.Divergence[int, string]) { reports <- d },
+			},
+		})
+
+	requestor := Go(ctx, m.Handler)
+
+	v := -3
+	resp, _ := requestor.Send(ctx, &types.Request[int, string, string]{Key: "math/square", Data: &v})
+	fmt.Println(*resp)
+
+	d := <-reports
+	fmt.Printf("%v from %s: expected %d, got %d\n", d.Kind, d.Variant, *d.Expected, *d.Actual)
+
+	// Output:
+	// 9
+	// response mismatch from shadow: expected 9, got 0
+}
+
+func TestRollout_StickyVariants(t *testing.T) {
+
+	reply := func(s string) types.Handler[int, string] {
+		return func(ctx context.Context, input *int) (*string, error) { return &s, nil }
+	}
+
+	m := mux.NewHandler[int, string, string](nil,
+		&mux.Register[int, string, string]{
+			Key:     "k",
+			Handler: reply("v1"),
+			Rollout: &mux.Rollout[int, string, string]{
+				Variants: []mux.Variant[int, string]{{Name: "v2", Weight: 5, Handler: reply("v2")}},
+				Sticky: func(ctx context.Context, t *int) string {
+					return *mux.Meta[string](ctx)
+				},
+			},
+		})
+
+	counts := map[string]int{}
+	for i := range 2000 {
+		user := fmt.Sprintf("user-%d", i)
+		var first string
+		for range 3 {
+			v := 0
+			resp, err := m.Handler(context.Background(), &types.Request[int, string, string]{Key: "k", Meta: user, Data: &v})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if first == "" {
+				first = *resp
+			} else if *resp != first {
+				t.Fatalf("%s was sent to both %s and %s", user, first, *resp)
+			}
+		}
+		counts[first]++
+	}
+
+	if counts["v2"] < 40 || counts["v2"] > 200 {
+		t.Fatalf("expected about 5%% of users to be sent to v2, got %v", counts)
+	}
+}
+
+func TestRollout_ErrorRateDifference(t *testing.T) {
+
+	errBroken := errors.New("broken")
+
+	var lck sync.Mutex
+	var reports []mux.Divergence[int, string]
+
+	m := mux.NewHandler[int, int, string](nil,
+		&mux.Register[int, int, string]{
+			Key:     "k",
+			Handler: func(ctx context.Context, input *int) (*int, error) { return input, nil },
+			Rollout: &mux.Rollout[int, int, string]{
+				Variants: []mux.Variant[int, int]{{
+					Name:    "v2",
+					Weight:  50,
+					Handler: func(ctx context.Context, input *int) (*int, error) { return nil, errBroken },
+				}},
+				Window:            100,
+				MaxErrorRateDelta: 0.1,
+				Report: func(d mux.Divergence[int, string]) {
+					lck.Lock()
+					defer lck.Unlock()
+					reports = append(reports, d)
+				},
+			},
+		})
+
+	for i := range 100 {
+		m.Handler(context.Background(), &types.Request[int, string, string]{Key: "k", Data: &i})
+	}
+
+	lck.Lock()
+	defer lck.Unlock()
+
+	if len(reports) != 1 {
+		t.Fatalf("expected 1 report, got %v", reports)
+	}
+	d := reports[0]
+	if d.Kind != mux.ErrorRateDifference || d.Variant != "v2" || d.ErrorRate != 1 || d.BaselineErrorRate != 0 {
+		t.Fatalf("unexpected report: %+v", d)
+	}
+}
+
+func TestRollout_Invalid(t *testing.T) {
+
+	h := func(ctx context.Context, input *int) (*int, error) { return input, nil }
+
+	tests := [][]mux.Variant[int, int]{
+		{{Name: "a", Weight: 60, Handler: h}, {Name: "b", Weight: 60, Handler: h}},
+		{{Name: "a", Weight: -1, Handler: h}},
+		{{Name: "a", Weight: 10}},
+	}
+
+	for i, variants := range tests {
+		_, err := mux.NewValidatedHandler[int, int, string](nil,
+			&mux.Register[int, int, string]{
+				Key:     "k",
+				Handler: h,
+				Rollout: &mux.Rollout[int, int, string]{Variants: variants},
+			})
+		if !errors.Is(err, mux.ErrInvalidRollout) {
+			t.Fatalf("%d: expected ErrInvalidRollout, got %v", i, err)
+		}
+	}
+}
+
+func TestRollout_ShadowCopies(t *testing.T) {
+
+	type order struct {
+		Items []string
+		Notes map[string]string
+	}
+
+	reports := make(chan mux.Divergence[order, string], 1)
+	release := make(chan struct{})
+
+	// The Handler and the caller both modify what they share with the Shadow
+	m := mux.NewHandler[order, order, string](nil,
+		&mux.Register[order, order, string]{
+			Key: "k",
+			Handler: func(ctx context.Context, o *order) (*order, error) {
+				resp := &order{Items: append([]string(nil), o.Items...), Notes: map[string]string{"status": "ok"}}
+				o.Items[0] = "changed"
+				return resp, nil
+			},
+			Rollout: &mux.Rollout[order, order, string]{
+				Shadow: func(ctx context.Context, o *order) (*order, error) {
+					<-release
+					return &order{Items: o.Items, Notes: map[string]string{"status": "ok"}}, nil
+				},
+				Report: func(d mux.Divergence[order, string]) { reports <- d },
+			},
+		})
+
+	resp, err := m.Handler(context.Background(), &types.Request[order, string, string]{Key: "k", Data: &order{Items: []string{"a"}}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Items[0] = "mine"
+	resp.Notes["status"] = "mine"
+	close(release)
+
+	select {
+	case d := <-reports:
+		t.Fatalf("unexpected report: %+v", d)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestRollout_MaxShadows(t *testing.T) {
+
+	var lck sync.Mutex
+	shadowed := 0
+	release := make(chan struct{})
+
+	h := func(ctx context.Context, input *int) (*int, error) { return input, nil }
+
+	m := mux.NewHandler[int, int, string](nil,
+		&mux.Register[int, int, string]{
+			Key:     "k",
+			Handler: h,
+			Rollout: &mux.Rollout[int, int, string]{
+				Shadow: func(ctx context.Context, input *int) (*int, error) {
+					lck.Lock()
+					shadowed++
+					lck.Unlock()
+					<-release
+					return input, nil
+				},
+				MaxShadows: 2,
+			},
+		})
+
+	for i := range 5 {
+		if _, err := m.Handler(context.Background(), &types.Request[int, string, string]{Key: "k", Data: &i}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	close(release)
+	time.Sleep(20 * time.Millisecond)
+
+	lck.Lock()
+	defer lck.Unlock()
+	if shadowed != 2 {
+		t.Fatalf("expected 2 requests to be shadowed, got %d", shadowed)
+	}
+}
@@ -0,0 +1,249 @@
+package saferr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gford1000-go/saferr/mux"
+	"github.com/gford1000-go/saferr/types"
+)
+
+func ExampleRegister_timeout() {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	slow := func(ctx context.Context, input *int) (*int, error) {
+		select {
+		case <-time.After(time.Second):
+		case <-ctx.Done():
+		}
+		return input, nil
+	}
+
+	m := mux.NewHandler[int, int, string](nil,
+		&mux.Register[int, int, string]{
+			Key:     "cache/get",
+			Handler: slow,
+			Timeout: 50 * time.Millisecond,
+		})
+
+	requestor := Go(ctx, m.Handler)
+
+	v := 1
+	_, err := requestor.Send(ctx, &types.Request[int, string, string]{Key: "cache/get", Data: &v})
+
+	fmt.Println(err)
+
+	// Output: handler timed out
+}
+
+func TestRegister_Middleware(t *testing.T) {
+
+	var calls []string
+	trace := func(name string) mux.Middleware[int, int] {
+		return func(next types.Handler[int, int]) types.Handler[int, int] {
+			return func(ctx context.Context, input *int) (*int, error) {
+				calls = append(calls, name)
+				return next(ctx, input)
+			}
+		}
+	}
+
+	m := mux.NewHandler[int, int, string](nil,
+		&mux.Register[int, int, string]{
+			Key: "a",
+			Handler: func(ctx context.Context, input *int) (*int, error) {
+				calls = append(calls, "handler")
+				return input, nil
+			},
+			Middleware: []mux.Middleware[int, int]{trace("outer"), trace("inner")},
+		})
+
+	v := 1
+	if _, err := m.Handler(context.Background(), &types.Request[int, string, string]{Key: "a", Data: &v}); err != nil {
+		t.Fatal(err)
+	}
+
+	if fmt.Sprint(calls) != "[outer inner handler]" {
+		t.Fatalf("unexpected call sequence: %v", calls)
+	}
+}
+
+func TestRegister_TimeoutPanic(t *testing.T) {
+
+	m := mux.NewHandler[int, int, string](nil,
+		&mux.Register[int, int, string]{
+			Key:     "a",
+			Handler: func(ctx context.Context, input *int) (*int, error) { panic("boom") },
+			Timeout: time.Second,
+		})
+
+	_, err := m.Handler(context.Background(), &types.Request[int, string, string]{Key: "a"})
+	if !errors.Is(err, ErrUncaughtHandlerPanic) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// concurrency runs n requests for key concurrently against m, returning the maximum number of
+// handlers observed running at the same time
+func concurrency(t *testing.T, key string, n int, regs func(h types.Handler[int, int]) []*mux.Register[int, int, string]) int32 {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var current, peak atomic.Int32
+	h := func(ctx context.Context, input *int) (*int, error) {
+		c := current.Add(1)
+		defer current.Add(-1)
+		for {
+			p := peak.Load()
+			if c <= p || peak.CompareAndSwap(p, c) {
+				break
+			}
+		}
+		<-time.After(20 * time.Millisecond)
+		return input, nil
+	}
+
+	m := mux.NewHandler[int, int, string](nil, regs(h)...)
+
+	requestor := Go(ctx, m.Handler, WithWorkers(8))
+
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := requestor.Send(ctx, &types.Request[int, string, string]{Key: key, Data: &i}); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	return peak.Load()
+}
+
+func TestRegister_MaxConcurrent(t *testing.T) {
+
+	peak := concurrency(t, "a", 16, func(h types.Handler[int, int]) []*mux.Register[int, int, string] {
+		return []*mux.Register[int, int, string]{
+			{Key: "a", Handler: h, ReadOnly: true, MaxConcurrent: 2},
+		}
+	})
+
+	if peak != 2 {
+		t.Fatalf("expected peak concurrency of 2, got %d", peak)
+	}
+}
+
+func TestRegister_ReadOnly(t *testing.T) {
+
+	peak := concurrency(t, "read", 16, func(h types.Handler[int, int]) []*mux.Register[int, int, string] {
+		return []*mux.Register[int, int, string]{
+			{Key: "read", Handler: h, ReadOnly: true},
+		}
+	})
+	if peak < 2 {
+		t.Fatalf("expected read only routes to run concurrently, got %d", peak)
+	}
+
+	peak = concurrency(t, "write", 16, func(h types.Handler[int, int]) []*mux.Register[int, int, string] {
+		return []*mux.Register[int, int, string]{
+			{Key: "write", Handler: h},
+		}
+	})
+	if peak != 1 {
+		t.Fatalf("expected other routes to run exclusively, got %d", peak)
+	}
+}
+
+func TestRegister_TimeoutHoldsRoute(t *testing.T) {
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+
+	// The first call ignores its context, and so outlives its Timeout
+	m := mux.NewHandler[int, int, string](nil,
+		&mux.Register[int, int, string]{
+			Key: "a",
+			Handler: func(ctx context.Context, input *int) (*int, error) {
+				if calls.Add(1) == 1 {
+					<-release
+				}
+				return input, nil
+			},
+			Timeout: 20 * time.Millisecond,
+		})
+
+	call := func() error {
+		v := 1
+		_, err := m.Handler(context.Background(), &types.Request[int, string, string]{Key: "a", Data: &v})
+		return err
+	}
+
+	first := make(chan error, 1)
+	go func() { first <- call() }()
+	for calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// The route is held, so the next call gives up waiting for it once its own Timeout has passed
+	if err := call(); !errors.Is(err, mux.ErrHandlerTimeout) {
+		t.Fatalf("expected ErrHandlerTimeout, got %v", err)
+	}
+
+	select {
+	case err := <-first:
+		t.Fatalf("expected the first call to wait for its handler, got %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	if err := <-first; !errors.Is(err, mux.ErrHandlerTimeout) {
+		t.Fatalf("expected ErrHandlerTimeout, got %v", err)
+	}
+
+	if err := call(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Fatalf("expected the timed out wait not to call the handler, got %d calls", n)
+	}
+}
+
+func TestRegister_MaxConcurrentCancelled(t *testing.T) {
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+
+	m := mux.NewHandler[int, int, string](nil,
+		&mux.Register[int, int, string]{
+			Key: "a",
+			Handler: func(ctx context.Context, input *int) (*int, error) {
+				close(started)
+				<-release
+				return input, nil
+			},
+			MaxConcurrent: 1,
+		})
+
+	v := 1
+	go m.Handler(context.Background(), &types.Request[int, string, string]{Key: "a", Data: &v})
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := m.Handler(ctx, &types.Request[int, string, string]{Key: "a", Data: &v})
+	if !errors.Is(err, context.DeadlineExceeded) || errors.Is(err, mux.ErrHandlerTimeout) {
+		t.Fatalf("expected the context error, got %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+package saferr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/gford1000-go/saferr/mux"
+)
+
+type Calculator struct {
+	calls int
+}
+
+func (c *Calculator) Square(ctx context.Context, input *int) (*int, error) {
+	c.calls++
+	result := *input * *input
+	return &result, nil
+}
+
+func (c *Calculator) Describe(ctx context.Context, input *float64) (*string, error) {
+	c.calls++
+	result := fmt.Sprintf("%.2f", *input)
+	return &result, nil
+}
+
+func ExampleNewServiceHandler() {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	th, err := mux.NewServiceHandler[string](&Calculator{})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	requestor := Go(ctx, th.Handler)
+
+	i := 4
+	square, _ := mux.Call(ctx, requestor, mux.NewRouteKey[int, int]("Calculator.Square"), &i)
+
+	f := 3.14159
+	description, _ := mux.Call(ctx, requestor, mux.NewRouteKey[float64, string]("Calculator.Describe"), &f)
+
+	fmt.Println(*square, *description)
+
+	// Output:
+	// 16 3.14
+}
+
+type badService struct{}
+
+func (badService) Good(ctx context.Context, input *int) (*int, error) { return input, nil }
+
+func (badService) NoContext(input *int) (*int, error) { return input, nil }
+
+func (badService) NoError(ctx context.Context, input *int) *int { return input }
+
+type onlyBad struct{}
+
+func (onlyBad) NoContext(input *int) (*int, error) { return input, nil }
+
+func TestRegisterService_Invalid(t *testing.T) {
+
+	th := mux.NewTypedHandler[string, string](nil)
+
+	err := mux.RegisterService(th, badService{})
+	if !errors.Is(err, mux.ErrInvalidService) {
+		t.Fatalf("expected ErrInvalidService, got %v", err)
+	}
+
+	var reported []string
+	for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var me *mux.MethodError
+		if errors.As(e, &me) {
+			reported = append(reported, me.Key)
+		}
+	}
+	if fmt.Sprint(reported) != "[badService.NoContext badService.NoError]" {
+		t.Fatalf("unexpected methods reported: %v", reported)
+	}
+
+	// The methods that fit are still registered
+	i := 1
+	var good any = &i
+	if resp, err := th.Handler(context.Background(), &mux.TypedRequest[string, string]{Key: "badService.Good", Data: &good}); err != nil || *(*resp).(*int) != 1 {
+		t.Fatalf("unexpected response: %v, %v", resp, err)
+	}
+	if _, err := th.Handler(context.Background(), &mux.TypedRequest[string, string]{Key: "badService.NoError"}); !errors.Is(err, mux.ErrHandlerNotFound) {
+		t.Fatalf("expected ErrHandlerNotFound, got %v", err)
+	}
+
+	if h, err := mux.NewServiceHandler[string](badService{}); h == nil || !errors.Is(err, mux.ErrInvalidService) {
+		t.Fatalf("expected a handler with skipped methods reported, got %v, %v", h, err)
+	}
+	if h, err := mux.NewServiceHandler[string](onlyBad{}); h != nil || !errors.Is(err, mux.ErrInvalidService) {
+		t.Fatalf("expected no handler, got %v, %v", h, err)
+	}
+
+	if err := mux.RegisterService(th, struct{}{}); !errors.Is(err, mux.ErrInvalidService) {
+		t.Fatalf("expected ErrInvalidService for service without methods, got %v", err)
+	}
+
+	if err := mux.RegisterService(th, nil); !errors.Is(err, mux.ErrInvalidService) {
+		t.Fatalf("expected ErrInvalidService for nil service, got %v", err)
+	}
+
+	calc := &Calculator{}
+	if err := mux.RegisterServiceName(th, "calc", calc); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mux.RegisterServiceName(th, "calc", calc); !errors.Is(err, mux.ErrDuplicateKey) {
+		t.Fatalf("expected ErrDuplicateKey, got %v", err)
+	}
+
+	var data any = &i
+	resp, err := th.Handler(context.Background(), &mux.TypedRequest[string, string]{Key: "calc.Square", Data: &data})
+	if err != nil || *(*resp).(*int) != 1 || calc.calls != 1 {
+		t.Fatalf("unexpected response: %v, %v", resp, err)
+	}
+
+	data = "wrong"
+	_, err = th.Handler(context.Background(), &mux.TypedRequest[string, string]{Key: "calc.Square", Data: &data})
+	if !errors.Is(err, mux.ErrTypeMismatch) {
+		t.Fatalf("expected ErrTypeMismatch, got %v", err)
+	}
+}
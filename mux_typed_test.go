@@ -0,0 +1,99 @@
+package saferr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gford1000-go/saferr/mux"
+)
+
+func ExampleRoute() {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	type customer struct {
+		Name string
+	}
+
+	m := mux.NewTypedHandler[string, string](nil)
+
+	getCustomer, _ := mux.Route(m, "customer/get", func(ctx context.Context, id *int) (*customer, error) {
+		return &customer{Name: fmt.Sprintf("customer-%d", *id)}, nil
+	})
+	upper, _ := mux.Route(m, "text/upper", func(ctx context.Context, s *string) (*string, error) {
+		result := strings.ToUpper(*s)
+		return &result, nil
+	})
+
+	requestor := Go(ctx, m.Handler)
+
+	id := 42
+	if c, err := mux.Call(ctx, requestor, getCustomer, &id); err != nil {
+		fmt.Println(err)
+	} else {
+		fmt.Println(c.Name)
+	}
+
+	s := "hello"
+	if u, err := mux.Call(ctx, requestor, upper, &s); err != nil {
+		fmt.Println(err)
+	} else {
+		fmt.Println(*u)
+	}
+
+	// Output:
+	// customer-42
+	// HELLO
+}
+
+func TestCall_TypeMismatch(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m := mux.NewTypedHandler[string, string](nil)
+	square, err := mux.Route(m, "square", func(ctx context.Context, i *int) (*int, error) {
+		result := *i * *i
+		return &result, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if square.Key() != "square" {
+		t.Fatalf("unexpected key: %v", square.Key())
+	}
+
+	requestor := Go(ctx, m.Handler)
+
+	// Wrong request type, from a RouteKey not returned by Route
+	s := "4"
+	_, err = mux.Call(ctx, requestor, mux.NewRouteKey[string, int]("square"), &s)
+	var mismatch *mux.TypeMismatchError
+	if !errors.As(err, &mismatch) || mismatch.Response {
+		t.Fatalf("expected request type mismatch, got: %v", err)
+	}
+
+	// Wrong response type
+	i := 4
+	_, err = mux.Call(ctx, requestor, mux.NewRouteKey[int, float64]("square"), &i)
+	if !errors.As(err, &mismatch) || !mismatch.Response {
+		t.Fatalf("expected response type mismatch, got: %v", err)
+	}
+	if !errors.Is(err, mux.ErrTypeMismatch) {
+		t.Fatalf("expected error to match ErrTypeMismatch: %v", err)
+	}
+
+	// Correct types
+	if u, err := mux.Call(ctx, requestor, square, &i); err != nil || *u != 16 {
+		t.Fatalf("unexpected result: %v, %v", u, err)
+	}
+
+	// Duplicate registration
+	if _, err := mux.Route(m, "square", func(ctx context.Context, i *int) (*int, error) { return i, nil }); !errors.Is(err, mux.ErrDuplicateKey) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
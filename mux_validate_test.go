@@ -0,0 +1,113 @@
+package saferr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/gford1000-go/saferr/mux"
+)
+
+func ExampleNewValidatedHandler() {
+
+	square := func(ctx context.Context, input *int) (*float64, error) {
+		var result float64 = float64(*input * *input)
+		return &result, nil
+	}
+
+	resolver := mux.NewResolver(
+		&mux.KeyResolver[string, string]{
+			Key: "math/{func}",
+			KeyResolver: func(key string, m *string) string {
+				return "math/" + *m
+			},
+			Targets: []string{"math/square", "math/cube"},
+		})
+
+	_, err := mux.NewValidatedHandler(resolver,
+		&mux.Register[int, float64, string]{Key: "math/square", Handler: square})
+
+	fmt.Println(err)
+
+	// Output: key resolver target has no handler: math/{func} resolves to math/cube
+}
+
+func TestNewValidatedHandler(t *testing.T) {
+
+	h := func(ctx context.Context, input *int) (*int, error) { return input, nil }
+	r := func(key string, m *string) string { return *m }
+
+	tests := []struct {
+		resolvers []*mux.KeyResolver[string, string]
+		handlers  []*mux.Register[int, int, string]
+		err       error
+	}{
+		{
+			handlers: []*mux.Register[int, int, string]{{Key: "a", Handler: h}, {Key: "a", Handler: h}},
+			err:      mux.ErrDuplicateKey,
+		},
+		{
+			handlers: []*mux.Register[int, int, string]{{Key: "a"}},
+			err:      mux.ErrNilHandler,
+		},
+		{
+			resolvers: []*mux.KeyResolver[string, string]{{Key: "{x}"}},
+			err:       mux.ErrNilResolver,
+		},
+		{
+			resolvers: []*mux.KeyResolver[string, string]{{Key: "{x}", KeyResolver: r}, {Key: "{x}", KeyResolver: r}},
+			err:       mux.ErrDuplicateKey,
+		},
+		{
+			resolvers: []*mux.KeyResolver[string, string]{{Key: "a", KeyResolver: r}},
+			handlers:  []*mux.Register[int, int, string]{{Key: "a", Handler: h}},
+			err:       mux.ErrShadowedResolver,
+		},
+		{
+			resolvers: []*mux.KeyResolver[string, string]{{Key: "{x}", KeyResolver: r, Targets: []string{"b"}}},
+			handlers:  []*mux.Register[int, int, string]{{Key: "a", Handler: h}},
+			err:       mux.ErrUnreachableTarget,
+		},
+		{
+			resolvers: []*mux.KeyResolver[string, string]{{Key: "{x}", KeyResolver: r, Targets: []string{"a"}}},
+			handlers:  []*mux.Register[int, int, string]{{Key: "a", Handler: h}},
+		},
+	}
+
+	for i, test := range tests {
+		_, err := mux.NewValidatedHandler(mux.NewResolver(test.resolvers...), test.handlers...)
+		if test.err == nil && err != nil {
+			t.Fatalf("%d: unexpected error: %v", i, err)
+		}
+		if !errors.Is(err, test.err) {
+			t.Fatalf("%d: expected %v, got %v", i, test.err, err)
+		}
+	}
+}
+
+func TestHandler_Routes(t *testing.T) {
+
+	h := func(ctx context.Context, input *int) (*int, error) { return input, nil }
+
+	resolver := mux.NewResolver(&mux.KeyResolver[string, string]{
+		Key:         "math/{func}",
+		KeyResolver: func(key string, m *string) string { return "math/" + *m },
+		Targets:     []string{"math/square"},
+	})
+
+	m := mux.NewHandler(resolver,
+		&mux.Register[int, int, string]{Key: "math/square", Handler: h},
+		&mux.Register[int, int, string]{Key: "echo", Handler: h})
+
+	routes := m.Routes()
+	if len(routes) != 3 {
+		t.Fatalf("unexpected routes: %v", routes)
+	}
+	if routes[0].Key != "math/square" || routes[1].Key != "echo" || routes[0].Resolved {
+		t.Fatalf("unexpected handler routes: %v", routes)
+	}
+	if !routes[2].Resolved || routes[2].Key != "math/{func}" || routes[2].Targets[0] != "math/square" {
+		t.Fatalf("unexpected resolver route: %v", routes[2])
+	}
+}
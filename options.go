@@ -13,7 +13,8 @@ type Options struct {
 	RequestorGoneAwayTimeout time.Duration
 	// ResponderTimeout is the timeout for ListenAndHandle to wait for requests before returning
 	ResponderTimeout time.Duration
-	// ChanSize sets the size of the communication buffer
+	// ChanSize sets the size of the communication buffer.  With fair queueing, it is the maximum number
+	// of requests that may be waiting across all the tenants
 	ChanSize int
 	// GoPreStart called by Go() to initialise the Responder go routine prior to listening for requests.
 	// GoPreStart returns the context that be used by ListenAndServe().
@@ -28,14 +29,65 @@ type Options struct {
 	// If ListenAndHandle() returns due to an error, GoPostListen will not be called.
 	GoPostListen func(context.Context) error
 	// CorrelatedChanSize sets the size of the buffer for chan that the Responder places resp[U] onto.
+	//
+	// Deprecated: the Responder now delivers each resp[U] directly to its Requestor, so this has no effect.
 	CorrelatedChanSize int
 	// CorrelatedChanRetries sets the number of times the correlatedChan will attempt to add a valid resp[U]
 	// onto the Requestor chan, before assuming the Requestor has gone away and discarding
+	//
+	// Deprecated: the Responder now delivers each resp[U] directly to its Requestor, so this has no effect.
 	CorrelatedChanRetries int
 	// CorrelatedChanAddTimeout sets the duration that the correlationChan will wait for the Requestor to
 	// receive the resp[U] on the Requestor chan, before timing out.  This is typically small, as the
 	// Requestor should be blocked to receive the resp[U].
+	//
+	// Deprecated: the Responder now delivers each resp[U] directly to its Requestor, so this has no effect.
 	CorrelatedChanAddTimeout time.Duration
+	// Workers sets the maximum number of requests that the Responder handles concurrently.
+	// The default of 1 handles each request to completion before taking the next, in FIFO sequence.
+	// With more than one, requests are still taken in FIFO sequence but may complete in any order,
+	// so the Handler must be safe for concurrent use.
+	Workers int
+	// Lanes sets the buffer size of each priority lane, highest priority first, replacing the single
+	// communication buffer sized by ChanSize.  Requests are taken in FIFO sequence within each lane,
+	// and in strict priority across the lanes, subject to LaneStarvationLimit
+	Lanes []int
+	// DefaultLane is the priority lane used by Send.  If negative, or there is no such lane, the lowest
+	// priority lane is used
+	DefaultLane int
+	// LaneStarvationLimit is the number of requests that may be taken from higher priority lanes whilst a
+	// lane has requests waiting, after which a request is taken from that lane
+	LaneStarvationLimit int
+	// TenantQueueSize is the maximum number of requests that may be waiting for each tenant when fair queueing
+	// is enabled using WithFairQueueing, beyond which requests are rejected with ErrTenantQueueFull
+	TenantQueueSize int
+	// TenantStatsLimit is the maximum number of tenants with no requests waiting whose TenantStats are kept
+	// when fair queueing is enabled.  Beyond this, the stats of the tenant idle the longest are discarded
+	TenantStatsLimit int
+	// TenantWeight returns the weight of a tenant when fair queueing is enabled, being the number of its requests
+	// taken in each round.  Defaults to 1 for every tenant
+	TenantWeight func(tenant string) int
+	// HotShardFactor is the multiple of the average depth of the shards of GoSharded above which
+	// a shard is reported as hot
+	HotShardFactor float64
+	// MaxBatchSize is the maximum number of requests passed together to the handler of GoBatch
+	MaxBatchSize int
+	// BatchLinger is how long GoBatch waits for further requests to fill a batch, after taking the first.
+	// The default of zero handles the requests that are already waiting, without delay
+	BatchLinger time.Duration
+	// Clock provides the time and timers used for all timeouts.  This is the wall clock
+	// unless replaced, typically with a FakeClock in tests
+	Clock Clock
+	// OnSnapshotError, if set, is called by GoActor with the error of a periodic snapshot
+	OnSnapshotError func(err error)
+	// actorRestore, actorSnapshot and actorSnapshotEvery hold the hooks for GoActor, set by
+	// WithRestore and WithSnapshot, whose types depend on the state of the actor
+	actorRestore       any
+	actorSnapshot      any
+	actorSnapshotEvery int
+	// fairKey holds the function set by WithFairQueueing, whose type depends on the request type,
+	// and which New checks against the request type of the Requestor
+	fairKey any
 }
 
 var defaults Options = Options{
@@ -46,6 +98,14 @@ var defaults Options = Options{
 	CorrelatedChanSize:       10,
 	CorrelatedChanRetries:    5,
 	CorrelatedChanAddTimeout: 100 * time.Millisecond,
+	Workers:                  1,
+	DefaultLane:              -1,
+	LaneStarvationLimit:      10,
+	TenantQueueSize:          100,
+	TenantStatsLimit:         1000,
+	HotShardFactor:           2,
+	MaxBatchSize:             100,
+	Clock:                    realClock{},
 }
 
 // WithChanSize sets the size of the communication buffer
@@ -108,6 +168,8 @@ func WithGoPostListen(f func(context.Context) error) func(*Options) {
 
 // WithCorrelatedChanSize sets the size of the buffer of the non-block chan *resp[U],
 // to which the Responder returns the result of the Handler call.  Default: 10
+//
+// Deprecated: see Options.CorrelatedChanSize.
 func WithCorrelatedChanSize(size int) func(*Options) {
 	return func(o *Options) {
 		if size > defaults.CorrelatedChanSize {
@@ -119,6 +181,8 @@ func WithCorrelatedChanSize(size int) func(*Options) {
 // WithCorrelatedChanRetries sets the number of retries that a correlatedChan[U] will
 // attempt, to transfer a valid resp[U] to its Requestor, before assuming that the
 // Requestor has gone away and discarding the resp[U].  Default: 5
+//
+// Deprecated: see Options.CorrelatedChanRetries.
 func WithCorrelatedChanRetries(retries int) func(*Options) {
 	return func(o *Options) {
 		if retries > defaults.CorrelatedChanRetries {
@@ -130,6 +194,8 @@ func WithCorrelatedChanRetries(retries int) func(*Options) {
 // WithCorrelatedChanAddTimeout sets the timeout duration for retries for the correlatedChan,
 // as it is attempting to add the resp[U] onto the chan that its Requestor should be blocked on.
 // This timeout should be short, and hence is floored at 100ms and capped at 1min
+//
+// Deprecated: see Options.CorrelatedChanAddTimeout.
 func WithCorrelatedChanAddTimeout(d time.Duration) func(*Options) {
 	return func(o *Options) {
 		if d > defaults.CorrelatedChanAddTimeout && d < time.Minute {
@@ -137,3 +203,127 @@ func WithCorrelatedChanAddTimeout(d time.Duration) func(*Options) {
 		}
 	}
 }
+
+// WithClock sets the Clock used for all timeouts, allowing a FakeClock to be used in tests
+func WithClock(c Clock) func(*Options) {
+	return func(o *Options) {
+		if c != nil {
+			o.Clock = c
+		}
+	}
+}
+
+// WithWorkers sets the maximum number of requests that the Responder handles concurrently.  Default: 1
+func WithWorkers(n int) func(*Options) {
+	return func(o *Options) {
+		if n > 0 {
+			o.Workers = n
+		}
+	}
+}
+
+// WithLanes sets the buffer size of each priority lane, highest priority first, so that requests sent
+// using SendWithPriority can overtake those in lower priority lanes.  The option is ignored if any size is
+// less than one, since skipping that lane would change the priority of those after it
+func WithLanes(sizes ...int) func(*Options) {
+	return func(o *Options) {
+		for _, size := range sizes {
+			if size < 1 {
+				return
+			}
+		}
+		if len(sizes) > 0 {
+			o.Lanes = append([]int(nil), sizes...)
+		}
+	}
+}
+
+// WithDefaultLane sets the priority lane used by Send.  Default: the lowest priority lane
+func WithDefaultLane(priority int) func(*Options) {
+	return func(o *Options) {
+		if priority >= 0 {
+			o.DefaultLane = priority
+		}
+	}
+}
+
+// WithLaneStarvationLimit sets the number of requests that may be taken from higher priority lanes whilst
+// a lane has requests waiting, after which a request is taken from that lane.  Default: 10
+func WithLaneStarvationLimit(n int) func(*Options) {
+	return func(o *Options) {
+		if n > 0 {
+			o.LaneStarvationLimit = n
+		}
+	}
+}
+
+// WithFairQueueing replaces the single communication buffer with a queue for each tenant, so that one
+// tenant cannot fill the buffer and starve the others.  key returns the tenant of each request, from the
+// context passed to Send or from the request itself (such as the Meta of a types.Request); if nil, the
+// tenant set by WithTenant or ForTenant is used.  Tenants are served by weighted round robin, see
+// WithTenantWeight.  The total number of requests waiting across all the tenants is limited to ChanSize,
+// beyond which requests are rejected with ErrUnableToSendRequest, and Lanes are ignored.  New panics with
+// ErrTenantKey if T is not the request type of the Requestor
+func WithFairQueueing[T any](key func(ctx context.Context, t *T) string) func(*Options) {
+	return func(o *Options) {
+		if key == nil {
+			key = func(ctx context.Context, _ *T) string { return TenantFromContext(ctx) }
+		}
+		o.fairKey = key
+	}
+}
+
+// WithTenantQueueSize sets the maximum number of requests that may be waiting for each tenant.  Default: 100
+func WithTenantQueueSize(size int) func(*Options) {
+	return func(o *Options) {
+		if size > 0 {
+			o.TenantQueueSize = size
+		}
+	}
+}
+
+// WithTenantStatsLimit sets the maximum number of tenants with no requests waiting whose TenantStats are
+// kept when fair queueing is enabled.  Default: 1000
+func WithTenantStatsLimit(n int) func(*Options) {
+	return func(o *Options) {
+		if n > 0 {
+			o.TenantStatsLimit = n
+		}
+	}
+}
+
+// WithTenantWeight sets the function returning the weight of each tenant, being the number of its requests
+// taken in each round.  Weights less than one are treated as one
+func WithTenantWeight(f func(tenant string) int) func(*Options) {
+	return func(o *Options) {
+		o.TenantWeight = f
+	}
+}
+
+// WithHotShardFactor sets the multiple of the average depth of the shards of GoSharded above which
+// a shard is reported as hot.  Default: 2
+func WithHotShardFactor(f float64) func(*Options) {
+	return func(o *Options) {
+		if f > 1 {
+			o.HotShardFactor = f
+		}
+	}
+}
+
+// WithMaxBatchSize sets the maximum number of requests passed together to the handler of GoBatch.  Default: 100
+func WithMaxBatchSize(n int) func(*Options) {
+	return func(o *Options) {
+		if n > 0 {
+			o.MaxBatchSize = n
+		}
+	}
+}
+
+// WithBatchLinger sets how long GoBatch waits for further requests to fill a batch, after taking the first
+func WithBatchLinger(d time.Duration) func(*Options) {
+	return func(o *Options) {
+		if d >= 0 {
+			o.BatchLinger = d
+		}
+	}
+}
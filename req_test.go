@@ -4,12 +4,11 @@ import (
 	"fmt"
 	"sync"
 	"testing"
-	"time"
 )
 
 func TestNewReqPool(t *testing.T) {
 
-	cp := newCorrelatedChanPool[int](5, 100*time.Millisecond, 10)
+	cp := newCorrelatedChanPool[int]()
 
 	p := newReqPool[int](cp, getIncrementer())
 
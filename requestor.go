@@ -2,6 +2,7 @@ package saferr
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 )
@@ -9,9 +10,26 @@ import (
 type requestor[T any, U any] struct {
 	commsBase[T, U]
 	pool *reqPool[T, U]
+	// fairKey returns the tenant of a request when fair queueing is enabled
+	fairKey func(context.Context, *T) string
 }
 
 func (r *requestor[T, U]) Send(ctx context.Context, t *T) (*U, error) {
+	return r.send(ctx, r.ch, t)
+}
+
+// SendWithPriority sends the request using the specified priority lane, where lane 0 has the highest priority
+func (r *requestor[T, U]) SendWithPriority(ctx context.Context, priority int, t *T) (*U, error) {
+	ch := r.ch
+	if r.lanes != nil && priority >= 0 && priority < len(r.lanes.ch) {
+		ch = r.lanes.ch[priority]
+	} else if priority != 0 {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidPriority, priority)
+	}
+	return r.send(ctx, ch, t)
+}
+
+func (r *requestor[T, U]) send(ctx context.Context, ch chan *req[T, U], t *T) (*U, error) {
 
 	select {
 	case <-r.ctx.Done():
@@ -24,11 +42,19 @@ func (r *requestor[T, U]) Send(ctx context.Context, t *T) (*U, error) {
 		if r.isClosed() {
 			return nil, ErrRequestorIsClosed
 		}
-		return r.attemptSend(t)
+		return r.attemptSend(ch, r.tenant(ctx, t), t)
+	}
+}
+
+// tenant returns the tenant of the request when fair queueing is enabled
+func (r *requestor[T, U]) tenant(ctx context.Context, t *T) string {
+	if r.fair == nil {
+		return ""
 	}
+	return r.fairKey(ctx, t)
 }
 
-func (r *requestor[T, U]) attemptSend(t *T) (u *U, err error) {
+func (r *requestor[T, U]) attemptSend(ch chan *req[T, U], tenant string, t *T) (u *U, err error) {
 	defer func() {
 		if rc := recover(); rc != nil {
 			u = nil
@@ -39,17 +65,39 @@ func (r *requestor[T, U]) attemptSend(t *T) (u *U, err error) {
 	// Get an initialised req[T, U] from the pool to reduce allocations
 	req := r.pool.Get(t)
 
-	// Deferred return of the req[T, U] (req) instance means that after a timeout
-	// for the Requestor, the Responder may attempt to send to its embedded chan after it has closed.
-	// Hence the trap for panic in Responder.sendResp(), which simply discards the resp.
-	defer r.pool.Put(req)
+	// Once req has been placed onto ch, the Responder may read it at any time until it has sent
+	// its resp[U].  So req is only returned to the pool if it was never sent, or once its resp[U]
+	// has been received.  Otherwise (e.g. after a timeout) it is left to the garbage collector,
+	// so that the Responder never sees a req that has been reset and reused by another Send().
+	recycle := true
+	defer func() {
+		if recycle {
+			r.pool.Put(req)
+		}
+	}()
 
 	retry := true
+
+	// With fair queueing the request is added to the queue of its tenant, which never blocks
+	if r.fair != nil {
+		select {
+		case <-r.done:
+			r.setClosed()
+			return nil, ErrCommsChannelIsClosed
+		default:
+		}
+		if err := r.fair.push(tenant, req); err != nil {
+			return nil, err
+		}
+		retry = false
+		recycle = false
+	}
+
 	attempts := 0
 	maxAttempts := 3
 	for retry {
 		var err error
-		submitTimer := acquireTimer(100 * time.Microsecond)
+		submitTimer := acquireTimer(r.clock, 100*time.Microsecond)
 
 		select {
 		case <-r.done:
@@ -57,17 +105,21 @@ func (r *requestor[T, U]) attemptSend(t *T) (u *U, err error) {
 			// that the Responder has closed and will not reply
 			r.setClosed()
 			err = ErrCommsChannelIsClosed
-		case r.ch <- req:
-			retry = false // only put the req onto the r.ch once
-		case <-submitTimer.C:
+		case ch <- req:
+			retry = false // only put the req onto the ch once
+			recycle = false
+			if r.lanes != nil {
+				r.lanes.notify()
+			}
+		case <-submitTimer.C():
 			// There is a possibility that a large number of concurrent Send() calls
-			// could fill up r.ch before the done chan is closed.
-			// This could mean that a Send() could block indefinitely trying to write to r.ch
+			// could fill up ch before the done chan is closed.
+			// This could mean that a Send() could block indefinitely trying to write to ch
 			// even though the Responder has closed.
 			// Retrying should detect done has closed, and so return an error
 			//
 			// Alternatively, the Responder could be very slow to respond,
-			// and so the Send() could be blocked trying to write to r.ch
+			// and so the Send() could be blocked trying to write to ch
 			// even though the Responder is taking a long time to respond.
 			// Hence don't call setClosed() as this might be a temporary condition
 			attempts++
@@ -91,13 +143,13 @@ func (r *requestor[T, U]) attemptSend(t *T) (u *U, err error) {
 	// Need to ensure ghost messages are captured and discarded.
 	// Also only allow the r.timeout duration to receive the correct resp for the req.
 	retry = true
-	responseTimer := acquireTimer(r.timeout)
+	responseTimer := acquireTimer(r.clock, r.timeout)
 	defer releaseTimer(responseTimer)
 
 	var resp *resp[U]
 	for retry {
 		select {
-		case <-responseTimer.C:
+		case <-responseTimer.C():
 			return nil, ErrSendTimeout
 		case resp = <-req.c.getReceiverChan():
 			if resp.id != req.id {
@@ -106,6 +158,7 @@ func (r *requestor[T, U]) attemptSend(t *T) (u *U, err error) {
 				resp.close()
 			} else {
 				retry = false // Have matched response
+				recycle = true
 			}
 		}
 	}
@@ -114,3 +167,17 @@ func (r *requestor[T, U]) attemptSend(t *T) (u *U, err error) {
 	defer resp.close()
 	return resp.data, resp.err
 }
+
+// neverQueued returns true if err means that Send returned without its request reaching the Responder
+func neverQueued(err error) bool {
+	if err == nil {
+		return false
+	}
+	return errors.Is(err, ErrContextCompleted) ||
+		errors.Is(err, ErrRequestorIsClosed) ||
+		errors.Is(err, ErrCommsChannelIsClosed) ||
+		errors.Is(err, ErrUnableToSendRequest) ||
+		errors.Is(err, ErrUncaughtSendPanic) ||
+		errors.Is(err, ErrInvalidPriority) ||
+		errors.Is(err, ErrTenantQueueFull)
+}
@@ -16,44 +16,107 @@ type responder[T any, U any] struct {
 	once                     sync.Once
 	initialise               sync.Once
 	pool                     *respPool[U]
+	workers                  chan struct{}
+	inflight                 sync.WaitGroup
 }
 
 func (r *responder[T, U]) ListenAndHandle(ctx context.Context, requestHandler types.Handler[T, U]) error {
+	req, err := r.listen(ctx)
+	if req == nil {
+		return err
+	}
+	return r.receive(ctx, requestHandler, req)
+}
+
+// listen waits for the next request, returning nil and no error if none arrives before the timeout
+func (r *responder[T, U]) listen(ctx context.Context) (*req[T, U], error) {
 	// Initialise the hasGoneAway time the first time ListenAndHandle is called
 	// allowing for other work to be done in the goroutine before the first request is handled
 	r.initialise.Do(func() {
-		r.hasGoneAway = time.Now().Add(r.requestorGoneAwayTimeout)
+		r.hasGoneAway = r.clock.Now().Add(r.requestorGoneAwayTimeout)
 	})
 
-	listenTimer := acquireTimer(r.timeout)
+	// With priority lanes or fair queueing, the request to be handled is chosen by the scheduler,
+	// and ready signals that a request may have been added
+	var ready <-chan struct{}
+	if r.sched != nil {
+		if req := r.sched.next(); req != nil {
+			return req, nil
+		}
+		ready = r.sched.ready()
+	}
+
+	listenTimer := acquireTimer(r.clock, r.timeout)
 	defer releaseTimer(listenTimer)
 
 	// Note: The caller is expected to loop on ListenAndHandle() from a single goroutine only
 	//       This is NOT thread safe if called from multiple goroutines, nor is request sequencing guaranteed
-	select {
-	case <-listenTimer.C:
-		if time.Now().After(r.hasGoneAway) {
+	for {
+		select {
+		case <-listenTimer.C():
+			if r.clock.Now().After(r.hasGoneAway) {
+				r.setClosed()
+				return nil, ErrRequestorGoneAway
+			}
+			return nil, nil
+		case <-r.ctx.Done():
+			r.setClosed()
+			return nil, ErrContextCompleted
+		case <-ctx.Done():
 			r.setClosed()
-			return ErrRequestorGoneAway
+			return nil, ErrContextCompleted
+		case req, ok := <-r.ch:
+			if !ok {
+				return nil, ErrCommsChannelIsClosed
+			}
+			return req, nil
+		case <-ready:
+			// The request may already have been taken, before the signal was received
+			if req := r.sched.next(); req != nil {
+				return req, nil
+			}
 		}
+	}
+}
+
+// receive handles a request taken from the chan, or chosen by the scheduler
+func (r *responder[T, U]) receive(ctx context.Context, requestHandler types.Handler[T, U], req *req[T, U]) error {
+	if r.isClosed() {
+		req.c.send(r.pool.Get(req.id, nil, ErrResponderIsClosed))
 		return nil
+	}
+	r.hasGoneAway = r.clock.Now().Add(r.requestorGoneAwayTimeout) // Reset the gone away timer
+	if r.workers != nil {
+		return r.dispatch(ctx, requestHandler, req)
+	}
+	return r.handle(ctx, requestHandler, req)
+}
+
+// dispatch handles the req in its own goroutine, once one of the workers is available.
+// Requests are still taken from the chan in FIFO sequence, but may complete in any order.
+func (r *responder[T, U]) dispatch(ctx context.Context, h types.Handler[T, U], req *req[T, U]) error {
+	select {
+	case r.workers <- struct{}{}:
 	case <-r.ctx.Done():
 		r.setClosed()
+		r.sendResp(req.c, r.pool.Get(req.id, nil, ErrContextCompleted))
 		return ErrContextCompleted
 	case <-ctx.Done():
 		r.setClosed()
+		r.sendResp(req.c, r.pool.Get(req.id, nil, ErrContextCompleted))
 		return ErrContextCompleted
-	case req, ok := <-r.ch:
-		if !ok {
-			return ErrCommsChannelIsClosed
-		}
-		if r.isClosed() {
-			req.c.send(r.pool.Get(req.id, nil, ErrResponderIsClosed))
-			return nil
-		}
-		r.hasGoneAway = time.Now().Add(r.requestorGoneAwayTimeout) // Reset the gone away timer
-		return r.handle(ctx, requestHandler, req)
 	}
+
+	r.inflight.Add(1)
+	go func() {
+		defer func() {
+			<-r.workers
+			r.inflight.Done()
+		}()
+		r.handle(ctx, h, req)
+	}()
+
+	return nil
 }
 
 func (r *responder[T, U]) Close() {
@@ -62,6 +125,8 @@ func (r *responder[T, U]) Close() {
 		close(r.done)
 		// close(r.ch) // Don't close the data channel - let this be garbage collected later
 	})
+	// Requests already dispatched to workers are allowed to complete
+	r.inflight.Wait()
 }
 
 func (r *responder[T, U]) sendResp(c *correlatedChan[U], resp *resp[U]) {
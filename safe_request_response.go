@@ -2,42 +2,82 @@ package saferr
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/gford1000-go/saferr/types"
 )
 
 // New returns a Requestor and Responder pair, that have a dedicated communication channel
-// that passes requests containing *T and responses containing *U.
+// that passes requests containing *T and responses containing *U.  New panics with ErrTenantKey
+// if WithFairQueueing was given a key function for a different request type.
 func New[T any, U any](ctx context.Context, opts ...func(*Options)) (types.Requestor[T, U], types.Responder[T, U]) {
 	var o Options = defaults
 	for _, f := range opts {
 		f(&o)
 	}
 
-	ch := make(chan *req[T, U], o.ChanSize)
+	if len(o.Lanes) == 1 {
+		o.ChanSize = o.Lanes[0]
+	}
+
+	// With priority lanes, Send uses the default lane and the Responder takes requests from all the lanes.
+	// With fair queueing, Send adds to the queue of the tenant and the Responder takes from all the queues
+	var l *lanes[T, U]
+	var fair *fairQueue[T, U]
+	var sched scheduler[T, U]
+	var ch, sendCh chan *req[T, U]
+	var fairKey func(context.Context, *T) string
+	if o.fairKey != nil {
+		var ok bool
+		if fairKey, ok = o.fairKey.(func(context.Context, *T) string); !ok {
+			panic(fmt.Errorf("%w: key is %T, requests are %T", ErrTenantKey, o.fairKey, (*T)(nil)))
+		}
+		fair = newFairQueue[T, U](o.TenantQueueSize, o.ChanSize, o.TenantStatsLimit, o.TenantWeight)
+		sched = fair
+	} else if len(o.Lanes) > 1 {
+		l = newLanes[T, U](o.Lanes, o.LaneStarvationLimit)
+		lane := o.DefaultLane
+		if lane < 0 || lane >= len(o.Lanes) {
+			lane = len(o.Lanes) - 1
+		}
+		sendCh = l.ch[lane]
+		sched = l
+	} else {
+		ch = make(chan *req[T, U], o.ChanSize)
+		sendCh = ch
+	}
 	done := make(chan struct{})
 
+	var workers chan struct{}
+	if o.Workers > 1 {
+		workers = make(chan struct{}, o.Workers)
+	}
+
 	return &requestor[T, U]{
 			commsBase: commsBase[T, U]{
-				ch:      ch,
+				ch:      sendCh,
+				lanes:   l,
+				fair:    fair,
 				done:    done,
 				ctx:     ctx,
 				timeout: o.RequestorTimeout,
+				clock:   o.Clock,
 			},
 			pool: newReqPool[T](
-				newCorrelatedChanPool[U](
-					o.CorrelatedChanRetries,
-					o.CorrelatedChanAddTimeout,
-					o.CorrelatedChanSize),
+				newCorrelatedChanPool[U](),
 				getIncrementer()),
+			fairKey: fairKey,
 		}, &responder[T, U]{
 			commsBase: commsBase[T, U]{
 				ch:      ch,
+				sched:   sched,
 				done:    done,
 				ctx:     ctx,
 				timeout: o.ResponderTimeout,
+				clock:   o.Clock,
 			},
 			pool:                     newRespPool[U](),
+			workers:                  workers,
 			requestorGoneAwayTimeout: o.RequestorGoneAwayTimeout,
 		}
 }
@@ -55,34 +95,39 @@ func Go[T any, U any](ctx context.Context, handler func(context.Context, *T) (*U
 		opt(&o)
 	}
 
-	go func() {
-		// Always ensure receiver resources are tidied up, and requestor knows it is not handling requests
-		defer receiver.Close()
+	go serve(ctx, receiver, &o, func(ctx context.Context) error {
+		return receiver.ListenAndHandle(ctx, handler)
+	})
 
-		var err error
-		defer func() {
-			if o.GoPostEnd != nil {
-				o.GoPostEnd(err)
-			}
-		}()
+	return requestor
+}
 
-		ctxLS := ctx
-		if o.GoPreStart != nil {
-			ctxLS, err = o.GoPreStart(ctx)
-		}
-		if err != nil {
-			return
-		}
+// serve runs the Responder goroutine for Go, calling the hooks in Options around repeated calls to listen
+func serve[T any, U any](ctx context.Context, receiver types.Responder[T, U], o *Options, listen func(context.Context) error) {
+	// Always ensure receiver resources are tidied up, and requestor knows it is not handling requests
+	defer receiver.Close()
 
-		for err == nil {
-			err = receiver.ListenAndHandle(ctxLS, handler)
-			if err == nil && o.GoPostListen != nil {
-				if err = o.GoPostListen(ctxLS); err != nil {
-					return
-				}
-			}
+	var err error
+	defer func() {
+		if o.GoPostEnd != nil {
+			o.GoPostEnd(err)
 		}
 	}()
 
-	return requestor
+	ctxLS := ctx
+	if o.GoPreStart != nil {
+		ctxLS, err = o.GoPreStart(ctx)
+	}
+	if err != nil {
+		return
+	}
+
+	for err == nil {
+		err = listen(ctxLS)
+		if err == nil && o.GoPostListen != nil {
+			if err = o.GoPostListen(ctxLS); err != nil {
+				return
+			}
+		}
+	}
 }
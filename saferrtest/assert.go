@@ -0,0 +1,60 @@
+package saferrtest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gford1000-go/saferr"
+)
+
+// AssertErrorIs fails the test if err does not match target using errors.Is
+func AssertErrorIs(t testing.TB, err, target error) {
+	t.Helper()
+	if !errors.Is(err, target) {
+		t.Errorf("expected error matching %q, got: %v", target, err)
+	}
+}
+
+// AssertCode fails the test if the Code of err is not code
+func AssertCode(t testing.TB, err error, code saferr.Code) {
+	t.Helper()
+	if c := saferr.CodeOf(err); c != code {
+		t.Errorf("expected error with code %v, got %v: %v", code, c, err)
+	}
+}
+
+// AssertSendTimeout fails the test if err is not saferr.ErrSendTimeout
+func AssertSendTimeout(t testing.TB, err error) {
+	t.Helper()
+	AssertErrorIs(t, err, saferr.ErrSendTimeout)
+}
+
+// AssertHandlerPanic fails the test if err is not saferr.ErrUncaughtHandlerPanic
+func AssertHandlerPanic(t testing.TB, err error) {
+	t.Helper()
+	AssertErrorIs(t, err, saferr.ErrUncaughtHandlerPanic)
+}
+
+// AssertRequestorGoneAway fails the test if err is not saferr.ErrRequestorGoneAway
+func AssertRequestorGoneAway(t testing.TB, err error) {
+	t.Helper()
+	AssertErrorIs(t, err, saferr.ErrRequestorGoneAway)
+}
+
+// AssertContextCompleted fails the test if err is not saferr.ErrContextCompleted
+func AssertContextCompleted(t testing.TB, err error) {
+	t.Helper()
+	AssertErrorIs(t, err, saferr.ErrContextCompleted)
+}
+
+// AssertClosed fails the test if err does not indicate that communication is no longer possible,
+// i.e. one of saferr.ErrRequestorIsClosed, saferr.ErrResponderIsClosed or saferr.ErrCommsChannelIsClosed
+func AssertClosed(t testing.TB, err error) {
+	t.Helper()
+	for _, target := range []error{saferr.ErrRequestorIsClosed, saferr.ErrResponderIsClosed, saferr.ErrCommsChannelIsClosed} {
+		if errors.Is(err, target) {
+			return
+		}
+	}
+	t.Errorf("expected a closed error, got: %v", err)
+}
@@ -0,0 +1,109 @@
+package saferrtest
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gford1000-go/saferr"
+	"github.com/gford1000-go/saferr/types"
+)
+
+// LeakTimeout is the duration that leak checks will wait for goroutines to exit, before
+// reporting them as leaked
+var LeakTimeout = 2 * time.Second
+
+const modulePath = "github.com/gford1000-go/saferr"
+
+// saferrGoroutines returns the stacks of all goroutines that were started by the
+// saferr module (excluding this package and any test code), keyed by goroutine id
+func saferrGoroutines() map[string]string {
+	buf := make([]byte, 1<<16)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+
+	result := map[string]string{}
+	for _, g := range strings.Split(string(buf), "\n\n") {
+		header, _, _ := strings.Cut(g, "\n")
+		id, ok := strings.CutPrefix(header, "goroutine ")
+		if !ok {
+			continue
+		}
+		id, _, _ = strings.Cut(id, " ")
+
+		if isSaferrCreated(g) {
+			result[id] = g
+		}
+	}
+	return result
+}
+
+// isSaferrCreated checks the "created by" frame of the goroutine stack
+func isSaferrCreated(stack string) bool {
+	_, createdBy, ok := strings.Cut(stack, "\ncreated by ")
+	if !ok {
+		return false
+	}
+	fn, location, _ := strings.Cut(createdBy, "\n")
+	if !strings.HasPrefix(fn, modulePath+".") && !strings.HasPrefix(fn, modulePath+"/") {
+		return false
+	}
+	if strings.HasPrefix(fn, modulePath+"/saferrtest.") {
+		return false
+	}
+	file, _, _ := strings.Cut(strings.TrimSpace(location), ":")
+	return !strings.HasSuffix(file, "_test.go")
+}
+
+// VerifyNoLeaks records the saferr goroutines currently running, and registers a Cleanup
+// that fails the test if additional saferr goroutines (such as Responder or correlatedChan
+// goroutines) remain alive after LeakTimeout.
+// Call this at the start of the test so that it is the last Cleanup to run.
+func VerifyNoLeaks(t testing.TB) {
+	t.Helper()
+
+	before := saferrGoroutines()
+
+	t.Cleanup(func() {
+		var leaked []string
+		deadline := time.Now().Add(LeakTimeout)
+		for {
+			leaked = leaked[:0]
+			for id, stack := range saferrGoroutines() {
+				if _, ok := before[id]; !ok {
+					leaked = append(leaked, stack)
+				}
+			}
+			if len(leaked) == 0 || time.Now().After(deadline) {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		if len(leaked) > 0 {
+			t.Errorf("found %d leaked saferr goroutine(s):\n\n%s", len(leaked), strings.Join(leaked, "\n\n"))
+		}
+	})
+}
+
+// Go is a test harness for saferr.Go.  The Responder goroutine is started with a context
+// that is cancelled when the test completes, and the test fails if any goroutines
+// started by saferr are still alive after that cleanup.
+func Go[T any, U any](t testing.TB, handler func(context.Context, *T) (*U, error), opts ...func(*saferr.Options)) types.Requestor[T, U] {
+	t.Helper()
+
+	VerifyNoLeaks(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	return saferr.Go(ctx, handler, opts...)
+}
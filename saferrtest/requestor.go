@@ -0,0 +1,89 @@
+package saferrtest
+
+import (
+	"context"
+	"reflect"
+	"sync"
+
+	"github.com/gford1000-go/saferr"
+	"github.com/gford1000-go/saferr/types"
+)
+
+// ErrNoScriptedResponse is returned by a FakeRequestor when no response has been scripted for the input
+var ErrNoScriptedResponse = &saferr.Error{Code: saferr.CodeUnimplemented, Message: "no scripted response for request"}
+
+type script[T any, U any] struct {
+	match func(*T) bool
+	u     *U
+	err   error
+}
+
+// FakeRequestor is a types.Requestor that returns scripted responses or errors, based on the input,
+// and records every request sent to it.  It is safe for concurrent use.
+type FakeRequestor[T any, U any] struct {
+	lck      sync.Mutex
+	scripts  []*script[T, U]
+	fallback *script[T, U]
+	calls    []*T
+}
+
+// NewFakeRequestor returns an unscripted FakeRequestor.  Until responses are scripted,
+// every Send returns ErrNoScriptedResponse
+func NewFakeRequestor[T any, U any]() *FakeRequestor[T, U] {
+	return &FakeRequestor[T, U]{}
+}
+
+// When scripts the response for any input for which match returns true.
+// Scripts are checked in the order they were added, with the first match used
+func (f *FakeRequestor[T, U]) When(match func(*T) bool, u *U, err error) *FakeRequestor[T, U] {
+	f.lck.Lock()
+	defer f.lck.Unlock()
+	f.scripts = append(f.scripts, &script[T, U]{match: match, u: u, err: err})
+	return f
+}
+
+// WhenEqual scripts the response for inputs that are deeply equal to t
+func (f *FakeRequestor[T, U]) WhenEqual(t T, u *U, err error) *FakeRequestor[T, U] {
+	return f.When(func(v *T) bool {
+		return v != nil && reflect.DeepEqual(*v, t)
+	}, u, err)
+}
+
+// Otherwise scripts the response for inputs that do not match any other script
+func (f *FakeRequestor[T, U]) Otherwise(u *U, err error) *FakeRequestor[T, U] {
+	f.lck.Lock()
+	defer f.lck.Unlock()
+	f.fallback = &script[T, U]{u: u, err: err}
+	return f
+}
+
+// Send records the request and returns the scripted response
+func (f *FakeRequestor[T, U]) Send(ctx context.Context, t *T) (*U, error) {
+	f.lck.Lock()
+	defer f.lck.Unlock()
+
+	f.calls = append(f.calls, t)
+
+	if ctx.Err() != nil {
+		return nil, saferr.ErrContextCompleted
+	}
+
+	for _, s := range f.scripts {
+		if s.match(t) {
+			return s.u, s.err
+		}
+	}
+	if f.fallback != nil {
+		return f.fallback.u, f.fallback.err
+	}
+	return nil, ErrNoScriptedResponse
+}
+
+// Calls returns the requests that have been sent, in the order received
+func (f *FakeRequestor[T, U]) Calls() []*T {
+	f.lck.Lock()
+	defer f.lck.Unlock()
+	return append([]*T(nil), f.calls...)
+}
+
+var _ types.Requestor[int, int] = &FakeRequestor[int, int]{}
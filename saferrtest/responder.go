@@ -0,0 +1,67 @@
+package saferrtest
+
+import (
+	"context"
+	"sync"
+
+	"github.com/gford1000-go/saferr/types"
+)
+
+// Record captures a single request handled by a RecordingResponder, along with its outcome
+type Record[T any, U any] struct {
+	Request  *T
+	Response *U
+	Err      error
+}
+
+// RecordingResponder is a types.Responder that wraps another Responder, recording every
+// request that it handles.  It is safe to read the records whilst requests are being handled.
+type RecordingResponder[T any, U any] struct {
+	types.Responder[T, U]
+	lck     sync.Mutex
+	records []Record[T, U]
+}
+
+// NewRecordingResponder returns a RecordingResponder that delegates to r.
+// r may be nil if the RecordingResponder is only used to Wrap handlers
+func NewRecordingResponder[T any, U any](r types.Responder[T, U]) *RecordingResponder[T, U] {
+	return &RecordingResponder[T, U]{Responder: r}
+}
+
+// ListenAndHandle delegates to the wrapped Responder, recording the requests handled
+func (r *RecordingResponder[T, U]) ListenAndHandle(ctx context.Context, requestHandler types.Handler[T, U]) error {
+	return r.Responder.ListenAndHandle(ctx, r.Wrap(requestHandler))
+}
+
+// Wrap returns a Handler that records each request before returning the result from h.
+// This allows recording when using saferr.Go, which manages its own Responder
+func (r *RecordingResponder[T, U]) Wrap(h types.Handler[T, U]) types.Handler[T, U] {
+	return func(ctx context.Context, t *T) (*U, error) {
+		u, err := h(ctx, t)
+
+		r.lck.Lock()
+		defer r.lck.Unlock()
+		r.records = append(r.records, Record[T, U]{Request: t, Response: u, Err: err})
+
+		return u, err
+	}
+}
+
+// Records returns the requests handled so far, in the order they were handled.
+// Requests whose handler panicked are not recorded
+func (r *RecordingResponder[T, U]) Records() []Record[T, U] {
+	r.lck.Lock()
+	defer r.lck.Unlock()
+	return append([]Record[T, U](nil), r.records...)
+}
+
+// Requests returns the requests handled so far, in the order they were handled
+func (r *RecordingResponder[T, U]) Requests() []*T {
+	r.lck.Lock()
+	defer r.lck.Unlock()
+	result := make([]*T, len(r.records))
+	for i, rec := range r.records {
+		result[i] = rec.Request
+	}
+	return result
+}
@@ -0,0 +1,185 @@
+package saferrtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gford1000-go/saferr"
+)
+
+// recordingTB captures failures and cleanups, so that the behaviour of the helpers can be verified
+type recordingTB struct {
+	testing.TB
+	cleanups []func()
+	errs     []string
+}
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Cleanup(f func()) {
+	r.cleanups = append(r.cleanups, f)
+}
+
+func (r *recordingTB) Errorf(format string, args ...any) {
+	r.errs = append(r.errs, fmt.Sprintf(format, args...))
+}
+
+func (r *recordingTB) runCleanups() {
+	for i := len(r.cleanups) - 1; i >= 0; i-- {
+		r.cleanups[i]()
+	}
+}
+
+func ExampleFakeRequestor() {
+
+	ctx := context.Background()
+
+	one, two := "one", "two"
+	requestor := NewFakeRequestor[int, string]().
+		WhenEqual(1, &one, nil).
+		WhenEqual(2, &two, nil).
+		Otherwise(nil, errors.New("unknown"))
+
+	for _, i := range []int{1, 2, 3} {
+		if response, err := requestor.Send(ctx, &i); err != nil {
+			fmt.Println(err)
+		} else {
+			fmt.Println(*response)
+		}
+	}
+
+	fmt.Println(len(requestor.Calls()))
+
+	// Output:
+	// one
+	// two
+	// unknown
+	// 3
+}
+
+func TestFakeRequestor_Unscripted(t *testing.T) {
+
+	requestor := NewFakeRequestor[int, int]()
+
+	i := 1
+	_, err := requestor.Send(context.Background(), &i)
+
+	AssertErrorIs(t, err, ErrNoScriptedResponse)
+	AssertCode(t, err, saferr.CodeUnimplemented)
+}
+
+func TestRecordingResponder(t *testing.T) {
+
+	recorder := NewRecordingResponder[int, int](nil)
+
+	double := func(ctx context.Context, i *int) (*int, error) {
+		if *i < 0 {
+			return nil, errors.New("negative")
+		}
+		result := *i * 2
+		return &result, nil
+	}
+
+	requestor := Go(t, recorder.Wrap(double))
+
+	for _, i := range []int{1, -1, 3} {
+		requestor.Send(context.Background(), &i)
+	}
+
+	records := recorder.Records()
+	if len(records) != 3 {
+		t.Fatalf("expected 3 records, got %d", len(records))
+	}
+	if *records[0].Request != 1 || *records[0].Response != 2 {
+		t.Fatalf("unexpected record: %v", records[0])
+	}
+	if records[1].Err == nil || records[1].Response != nil {
+		t.Fatalf("expected error to be recorded: %v", records[1])
+	}
+	if *recorder.Requests()[2] != 3 {
+		t.Fatalf("unexpected request: %v", *recorder.Requests()[2])
+	}
+}
+
+func TestVerifyNoLeaks(t *testing.T) {
+
+	defer func(d time.Duration) { LeakTimeout = d }(LeakTimeout)
+	LeakTimeout = 100 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tb := &recordingTB{TB: t}
+
+	VerifyNoLeaks(tb)
+
+	// The Responder goroutine is still running when the cleanup is made
+	saferr.Go(ctx, func(ctx context.Context, i *int) (*int, error) { return i, nil })
+
+	tb.runCleanups()
+
+	if len(tb.errs) != 1 || !strings.Contains(tb.errs[0], "saferr.Go") {
+		t.Fatalf("expected leaked Responder goroutine to be reported, got: %v", tb.errs)
+	}
+}
+
+func TestGo(t *testing.T) {
+
+	tb := &recordingTB{TB: t}
+
+	Go(tb, func(ctx context.Context, i *int) (*int, error) { return i, nil })
+
+	// Cleanup cancels the context, so the Responder goroutine should exit
+	tb.runCleanups()
+
+	if len(tb.errs) != 0 {
+		t.Fatalf("unexpected leak reported: %v", tb.errs)
+	}
+}
+
+func TestGo_WithRequests(t *testing.T) {
+
+	tb := &recordingTB{TB: t}
+
+	requestor := Go(tb, func(ctx context.Context, i *int) (*int, error) { return i, nil })
+
+	// Concurrent requests use many pooled correlatedChans, none of which should outlive the Responder
+	var wg sync.WaitGroup
+	for i := range 100 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			requestor.Send(context.Background(), &i)
+		}()
+	}
+	wg.Wait()
+
+	tb.runCleanups()
+
+	if len(tb.errs) != 0 {
+		t.Fatalf("unexpected leak reported: %v", tb.errs)
+	}
+}
+
+func TestAssertions(t *testing.T) {
+
+	tb := &recordingTB{TB: t}
+
+	AssertSendTimeout(tb, fmt.Errorf("wrapped: %w", saferr.ErrSendTimeout))
+	AssertHandlerPanic(tb, fmt.Errorf("%w: %v", saferr.ErrUncaughtHandlerPanic, "boom"))
+	AssertClosed(tb, saferr.ErrCommsChannelIsClosed)
+	if len(tb.errs) != 0 {
+		t.Fatalf("unexpected failures: %v", tb.errs)
+	}
+
+	AssertRequestorGoneAway(tb, saferr.ErrContextCompleted)
+	AssertClosed(tb, saferr.ErrSendTimeout)
+	if len(tb.errs) != 2 {
+		t.Fatalf("expected 2 failures, got: %v", tb.errs)
+	}
+}
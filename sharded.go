@@ -0,0 +1,266 @@
+package saferr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gford1000-go/saferr/types"
+)
+
+// ErrInvalidShards returned if the number of shards requested is less than one
+var ErrInvalidShards = &Error{Code: CodeInvalidArgument, Message: "invalid number of shards"}
+
+// ShardStats describes the requests of a shard of a Sharded Requestor
+type ShardStats struct {
+	// Depth is the number of requests sent to the shard that have not yet received a response
+	Depth int64
+	// Handled is the number of requests that have received a response (a value or a handler error) from
+	// the Responder of the shard since it was started
+	Handled uint64
+	// Hot is true if the Depth of the shard is more than the HotShardFactor multiple of the average
+	// Depth of all the shards, suggesting that its keys are unevenly loaded
+	Hot bool
+}
+
+type shard[T any, U any] struct {
+	requestor types.Requestor[T, U]
+	cancel    context.CancelFunc
+	depth     atomic.Int64
+	handled   atomic.Uint64
+}
+
+// inflight counts the requests sent to a shard whilst the shards have one length, closing drained
+// once a Resize has changed the length and all of those requests have been handled
+type inflight struct {
+	wg      sync.WaitGroup
+	drained chan struct{}
+}
+
+func newInflight(n int) []*inflight {
+	f := make([]*inflight, n)
+	for i := range f {
+		f[i] = &inflight{drained: make(chan struct{})}
+	}
+	return f
+}
+
+// Sharded is a Requestor that sends each request to one of several Responders, chosen by the key of
+// the request, so that requests with the same key are handled one at a time in FIFO sequence whilst
+// requests with different keys may be handled in parallel
+type Sharded[T any, U any] struct {
+	// lck guards shards, sent and moving, and is held by Send only whilst it picks a shard, so that
+	// a handler may Send to the Sharded during a Resize
+	lck sync.RWMutex
+	// resizing is held by Resize, so that keys move at most one Resize at a time
+	resizing  sync.Mutex
+	ctx       context.Context
+	key       func(ctx context.Context, t *T) string
+	handler   func(context.Context, *T) (*U, error)
+	opts      []func(*Options)
+	hotFactor float64
+	shards    []*shard[T, U]
+	// sent counts the requests sent to each shard since the last Resize, and moving those sent
+	// to each shard before it, until they have been handled
+	sent   []*inflight
+	moving []*inflight
+}
+
+// GoSharded behaves as Go, except that requests are handled by n Responders, each in its own goroutine.
+// Each request is sent to the Responder chosen by consistent hashing of the key returned by key, so that
+// requests with the same key (such as an account id) retain the FIFO guarantee of Go, whilst the service
+// scales across keys.  The Workers option is ignored, since each Responder must handle one request at a time
+func GoSharded[T any, U any](ctx context.Context, n int, key func(ctx context.Context, t *T) string, handler func(context.Context, *T) (*U, error), opts ...func(*Options)) (*Sharded[T, U], error) {
+	if n < 1 {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidShards, n)
+	}
+
+	o := defaults
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	s := &Sharded[T, U]{
+		ctx:       ctx,
+		key:       key,
+		handler:   handler,
+		opts:      append(opts[:len(opts):len(opts)], WithWorkers(1)),
+		hotFactor: o.HotShardFactor,
+	}
+	s.resize(n)
+	return s, nil
+}
+
+// Send sends the request to the shard for its key.  If the key moved shard in the last Resize, the
+// request waits until the requests sent for the key to its previous shard have been handled, so a
+// handler that calls Send on the same Sharded must only do so for keys of other shards
+func (s *Sharded[T, U]) Send(ctx context.Context, t *T) (*U, error) {
+	h := s.hash(ctx, t)
+
+	s.lck.RLock()
+	i := jumpHash(h, len(s.shards))
+	sh, sent := s.shards[i], s.sent[i]
+	sent.wg.Add(1)
+	var drained chan struct{}
+	if s.moving != nil {
+		if j := jumpHash(h, len(s.moving)); j != i {
+			drained = s.moving[j].drained
+		}
+	}
+	s.lck.RUnlock()
+	defer sent.wg.Done()
+
+	sh.depth.Add(1)
+	defer sh.depth.Add(-1)
+
+	if drained != nil {
+		select {
+		case <-drained:
+		case <-ctx.Done():
+			return nil, ErrContextCompleted
+		}
+	}
+
+	// Failures to send, and requests that timed out or were refused, did not receive a response from the shard
+	u, err := sh.requestor.Send(ctx, t)
+	if !neverQueued(err) && !errors.Is(err, ErrSendTimeout) && !errors.Is(err, ErrResponderIsClosed) {
+		sh.handled.Add(1)
+	}
+	return u, err
+}
+
+// Shard returns the index of the shard to which the request would be sent
+func (s *Sharded[T, U]) Shard(ctx context.Context, t *T) int {
+	s.lck.RLock()
+	defer s.lck.RUnlock()
+	return jumpHash(s.hash(ctx, t), len(s.shards))
+}
+
+func (s *Sharded[T, U]) hash(ctx context.Context, t *T) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s.key(ctx, t)))
+	return h.Sum64()
+}
+
+// Len returns the number of shards
+func (s *Sharded[T, U]) Len() int {
+	s.lck.RLock()
+	defer s.lck.RUnlock()
+	return len(s.shards)
+}
+
+// Resize changes the number of shards to n, returning once new requests are sent to the new shards.
+// Requests for a key that moves wait whilst the requests already sent for it are handled, so that no
+// key has requests in two shards at once, and so the FIFO sequence of each key is kept.  Consistent
+// hashing means that only the keys that must move to a new shard (or from a removed shard) do so.
+// Removed shards are stopped once their requests have been handled.  Resize waits for the keys moved
+// by the previous Resize to be settled before moving any more.
+func (s *Sharded[T, U]) Resize(n int) error {
+	if n < 1 {
+		return fmt.Errorf("%w: %d", ErrInvalidShards, n)
+	}
+
+	s.resizing.Lock()
+	defer s.resizing.Unlock()
+
+	s.lck.RLock()
+	moving := s.moving
+	s.lck.RUnlock()
+	for _, f := range moving {
+		<-f.drained
+	}
+
+	s.lck.Lock()
+	defer s.lck.Unlock()
+
+	s.resize(n)
+	return nil
+}
+
+func (s *Sharded[T, U]) resize(n int) {
+	// Copied, since the shards may grow again over the same array
+	removed := append([]*shard[T, U](nil), s.shards[min(n, len(s.shards)):]...)
+	for len(s.shards) < n {
+		ctx, cancel := context.WithCancel(s.ctx)
+		s.shards = append(s.shards, &shard[T, U]{
+			requestor: Go(ctx, s.handler, s.opts...),
+			cancel:    cancel,
+		})
+	}
+	s.shards = s.shards[:n]
+
+	moving := s.sent
+	s.sent = newInflight(n)
+	if moving == nil {
+		return
+	}
+	s.moving = moving
+
+	// No more requests are counted by moving once lck is released
+	for _, f := range moving {
+		go func() {
+			f.wg.Wait()
+			close(f.drained)
+		}()
+	}
+	go func() {
+		for _, f := range moving {
+			<-f.drained
+		}
+		for _, sh := range removed {
+			sh.cancel()
+		}
+
+		s.lck.Lock()
+		defer s.lck.Unlock()
+		if len(s.moving) > 0 && s.moving[0] == moving[0] {
+			s.moving = nil
+		}
+	}()
+}
+
+// Stats returns the ShardStats of each shard
+func (s *Sharded[T, U]) Stats() []ShardStats {
+	s.lck.RLock()
+	defer s.lck.RUnlock()
+
+	stats := make([]ShardStats, len(s.shards))
+	var total int64
+	for i, sh := range s.shards {
+		stats[i] = ShardStats{Depth: sh.depth.Load(), Handled: sh.handled.Load()}
+		total += stats[i].Depth
+	}
+
+	mean := float64(total) / float64(len(stats))
+	for i := range stats {
+		// A single waiting request is never considered hot
+		stats[i].Hot = stats[i].Depth > 1 && float64(stats[i].Depth) > s.hotFactor*mean
+	}
+	return stats
+}
+
+// HotShards returns the indexes of the shards whose ShardStats are Hot
+func (s *Sharded[T, U]) HotShards() []int {
+	var hot []int
+	for i, st := range s.Stats() {
+		if st.Hot {
+			hot = append(hot, i)
+		}
+	}
+	return hot
+}
+
+// jumpHash is the jump consistent hash of Lamping and Veach, returning a bucket in [0, n)
+// such that only 1/n of keys move when the number of buckets changes to n
+func jumpHash(key uint64, n int) int {
+	var b, j int64 = -1, 0
+	for j < int64(n) {
+		b = j
+		key = key*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+	return int(b)
+}
@@ -0,0 +1,404 @@
+package saferr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+type transfer struct {
+	Account string
+	Amount  int
+}
+
+func accountKey(_ context.Context, t *transfer) string {
+	return t.Account
+}
+
+func ExampleGoSharded() {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The map is shared by all the shards, but each account is only handled by one goroutine at a time
+	var lck sync.Mutex
+	balances := map[string]int{}
+
+	handler := func(ctx context.Context, t *transfer) (*int, error) {
+		lck.Lock()
+		defer lck.Unlock()
+		balances[t.Account] += t.Amount
+		b := balances[t.Account]
+		return &b, nil
+	}
+
+	requestor, err := GoSharded(ctx, 4, accountKey, handler)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	requestor.Send(ctx, &transfer{Account: "alice", Amount: 10})
+	b, _ := requestor.Send(ctx, &transfer{Account: "alice", Amount: -3})
+	fmt.Println(*b)
+
+	// Output:
+	// 7
+}
+
+// keyTracker fails the test if two requests for the same key are handled at once
+type keyTracker struct {
+	t      *testing.T
+	lck    sync.Mutex
+	active map[string]bool
+}
+
+func (k *keyTracker) handle(ctx context.Context, tr *transfer) (*int, error) {
+	k.lck.Lock()
+	if k.active[tr.Account] {
+		k.t.Errorf("account %s handled concurrently", tr.Account)
+	}
+	k.active[tr.Account] = true
+	k.lck.Unlock()
+
+	time.Sleep(50 * time.Microsecond)
+
+	k.lck.Lock()
+	k.active[tr.Account] = false
+	k.lck.Unlock()
+	return &tr.Amount, nil
+}
+
+func TestGoSharded_PerKeySequence(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	k := &keyTracker{t: t, active: map[string]bool{}}
+
+	// Most of the requests may be sent to one shard, so each buffer must hold them all
+	requestor, err := GoSharded(ctx, 4, accountKey, k.handle, WithWorkers(8), WithChanSize(400))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := range 400 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tr := &transfer{Account: fmt.Sprint("acc-", i%10), Amount: i}
+			u, err := requestor.Send(ctx, tr)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if *u != i {
+				t.Errorf("expected %d, got %d", i, *u)
+			}
+		}()
+		if i == 200 {
+			go func() {
+				if err := requestor.Resize(7); err != nil {
+					t.Error(err)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+
+	if requestor.Len() != 7 {
+		t.Fatalf("expected 7 shards, got %d", requestor.Len())
+	}
+
+	var handled uint64
+	for _, s := range requestor.Stats() {
+		handled += s.Handled
+	}
+	if handled != 400 {
+		t.Fatalf("expected 400 requests handled, got %d", handled)
+	}
+}
+
+func TestGoSharded_Parallel(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	started := make(chan string, 2)
+	release := make(chan struct{})
+	handler := func(ctx context.Context, tr *transfer) (*int, error) {
+		started <- tr.Account
+		<-release
+		return &tr.Amount, nil
+	}
+
+	requestor, err := GoSharded(ctx, 8, accountKey, handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Find two accounts on different shards
+	a := &transfer{Account: "a"}
+	b := &transfer{Account: "b"}
+	for i := 0; requestor.Shard(ctx, a) == requestor.Shard(ctx, b); i++ {
+		b.Account = fmt.Sprint("b", i)
+	}
+
+	var wg sync.WaitGroup
+	for _, tr := range []*transfer{a, b} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			requestor.Send(ctx, tr)
+		}()
+	}
+
+	// Both are handled at once
+	for range 2 {
+		select {
+		case <-started:
+		case <-time.After(time.Second):
+			t.Fatal("requests for different shards not handled in parallel")
+		}
+	}
+	close(release)
+	wg.Wait()
+}
+
+func TestGoSharded_HotShards(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	release := make(chan struct{})
+	handler := func(ctx context.Context, tr *transfer) (*int, error) {
+		<-release
+		return &tr.Amount, nil
+	}
+
+	requestor, err := GoSharded(ctx, 4, accountKey, handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hot := &transfer{Account: "hot"}
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			requestor.Send(ctx, hot)
+		}()
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for requestor.Stats()[requestor.Shard(ctx, hot)].Depth != 5 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected depth 5, got %+v", requestor.Stats())
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if got := requestor.HotShards(); len(got) != 1 || got[0] != requestor.Shard(ctx, hot) {
+		t.Fatalf("expected shard %d to be hot, got %v", requestor.Shard(ctx, hot), got)
+	}
+
+	close(release)
+	wg.Wait()
+
+	if got := requestor.HotShards(); len(got) != 0 {
+		t.Fatalf("expected no hot shards, got %v", got)
+	}
+}
+
+func TestGoSharded_InvalidShards(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	handler := func(ctx context.Context, tr *transfer) (*int, error) { return &tr.Amount, nil }
+
+	if _, err := GoSharded(ctx, 0, accountKey, handler); !errors.Is(err, ErrInvalidShards) {
+		t.Fatalf("expected ErrInvalidShards, got %v", err)
+	}
+
+	requestor, _ := GoSharded(ctx, 2, accountKey, handler)
+	if err := requestor.Resize(-1); !errors.Is(err, ErrInvalidShards) {
+		t.Fatalf("expected ErrInvalidShards, got %v", err)
+	}
+}
+
+func TestJumpHash(t *testing.T) {
+
+	// Growing from 10 to 11 buckets moves about 1/11 of the keys, all to the new bucket
+	moved := 0
+	for k := range uint64(10000) {
+		before, after := jumpHash(k, 10), jumpHash(k, 11)
+		if before != after {
+			if after != 10 {
+				t.Fatalf("key %d moved from %d to %d", k, before, after)
+			}
+			moved++
+		}
+	}
+	if moved < 700 || moved > 1100 {
+		t.Fatalf("expected about 909 keys to move, got %d", moved)
+	}
+}
+
+func TestGoSharded_HandledCount(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	handler := func(ctx context.Context, tr *transfer) (*int, error) {
+		if tr.Amount < 0 {
+			return nil, errors.New("negative amount")
+		}
+		return &tr.Amount, nil
+	}
+
+	requestor, err := GoSharded(ctx, 1, accountKey, handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	requestor.Send(ctx, &transfer{Account: "a", Amount: 1})
+	requestor.Send(ctx, &transfer{Account: "a", Amount: -1})
+
+	// Requests that never reach the shard are not counted
+	callerCtx, callerCancel := context.WithCancel(ctx)
+	callerCancel()
+	if _, err := requestor.Send(callerCtx, &transfer{Account: "a", Amount: 1}); !errors.Is(err, ErrContextCompleted) {
+		t.Fatalf("expected ErrContextCompleted, got %v", err)
+	}
+
+	if h := requestor.Stats()[0].Handled; h != 2 {
+		t.Fatalf("expected 2 requests handled, got %d", h)
+	}
+}
+
+func TestGoSharded_ResizeReentrant(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var requestor *Sharded[transfer, int]
+	var inner string
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := func(ctx context.Context, tr *transfer) (*int, error) {
+		if tr.Account != "outer" {
+			return &tr.Amount, nil
+		}
+		close(started)
+		<-release
+		// A handler may Send to the Sharded, for keys of other shards, during a Resize
+		return requestor.Send(ctx, &transfer{Account: inner, Amount: tr.Amount + 1})
+	}
+
+	requestor, err := GoSharded(ctx, 2, accountKey, handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outer := requestor.Shard(ctx, &transfer{Account: "outer"})
+	for i := 0; inner == ""; i++ {
+		if k := fmt.Sprint("acc-", i); requestor.Shard(ctx, &transfer{Account: k}) != outer {
+			inner = k
+		}
+	}
+
+	result := make(chan int)
+	go func() {
+		u, err := requestor.Send(ctx, &transfer{Account: "outer", Amount: 1})
+		if err != nil {
+			t.Error(err)
+		}
+		result <- *u
+	}()
+	<-started
+
+	resized := make(chan struct{})
+	go func() {
+		requestor.Resize(8)
+		close(resized)
+	}()
+
+	select {
+	case <-resized:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Resize blocked by the request in flight")
+	}
+
+	close(release)
+	select {
+	case u := <-result:
+		if u != 2 {
+			t.Fatalf("expected 2, got %d", u)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("re-entrant Send deadlocked")
+	}
+}
+
+func TestGoSharded_ResizeMovedKeyWaits(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	handler := func(ctx context.Context, tr *transfer) (*int, error) {
+		if tr.Amount == 1 {
+			started <- struct{}{}
+			<-release
+		}
+		return &tr.Amount, nil
+	}
+
+	requestor, err := GoSharded(ctx, 1, accountKey, handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A key that moves from the only shard when there are two
+	var key string
+	for i := 0; key == ""; i++ {
+		if k := fmt.Sprint("acc-", i); jumpHash(requestor.hash(ctx, &transfer{Account: k}), 2) == 1 {
+			key = k
+		}
+	}
+
+	go requestor.Send(ctx, &transfer{Account: key, Amount: 1})
+	<-started
+
+	if err := requestor.Resize(2); err != nil {
+		t.Fatal(err)
+	}
+
+	// The second request for the key must not overtake the first, still held by its previous shard
+	done := make(chan struct{})
+	go func() {
+		requestor.Send(ctx, &transfer{Account: key, Amount: 2})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("moved key handled before the request sent to its previous shard")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("moved key never handled")
+	}
+}
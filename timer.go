@@ -8,22 +8,28 @@ import (
 // time.Timer instances (embedded in time.After) are memory heavy
 // so use a Pool to reuse and reduce allocation overhead
 var timerPool = sync.Pool{
-	New: func() any { return time.NewTimer(0) },
+	New: func() any { return &realTimer{t: time.NewTimer(0)} },
 }
 
-func acquireTimer(d time.Duration) *time.Timer {
-	t := timerPool.Get().(*time.Timer)
+// acquireTimer returns a Timer from the Clock, using the pool when the Clock is the wall clock
+func acquireTimer(c Clock, d time.Duration) Timer {
+	if _, ok := c.(realClock); !ok {
+		return c.NewTimer(d)
+	}
+	t := timerPool.Get().(*realTimer)
 	t.Reset(d)
 	return t
 }
 
-func releaseTimer(t *time.Timer) {
+func releaseTimer(t Timer) {
 	if !t.Stop() {
 		// Use select with default to avoid blocking
 		select {
-		case <-t.C:
+		case <-t.C():
 		default:
 		}
 	}
-	timerPool.Put(t)
+	if rt, ok := t.(*realTimer); ok {
+		timerPool.Put(rt)
+	}
 }
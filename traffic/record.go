@@ -0,0 +1,191 @@
+package traffic
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gford1000-go/saferr"
+	"github.com/gford1000-go/saferr/types"
+)
+
+// ErrWriterClosed is returned if a Record is written after the Writer is closed
+var ErrWriterClosed = &types.Error{Code: types.CodeFailedPrecondition, Message: "recording writer is closed"}
+
+// Record is a single request captured by Go, with the response or error returned by the handler
+type Record[T, U any] struct {
+	// ID of the request, in the sequence sent
+	ID uint64
+	// Time the request was sent
+	Time time.Time
+	// Request sent
+	Request *T
+	// Response returned by the handler
+	Response *U
+	// Err returned by the handler, if any
+	Err *types.Error
+	// QueueWait is the time between the request being sent and the handler starting
+	QueueWait time.Duration
+	// HandlerTime is the duration of the handler
+	HandlerTime time.Duration
+}
+
+// Writer writes Records to a recording, using a Codec.  It is safe for concurrent use
+type Writer[T, U any] struct {
+	lck    sync.Mutex
+	w      *bufio.Writer
+	c      io.Closer
+	codec  saferr.Codec[Record[T, U]]
+	err    error
+	closed bool
+}
+
+// NewWriter returns a Writer of Records to w, encoded by codec.  If codec is nil, saferr.JSONCodec is used
+func NewWriter[T, U any](w io.Writer, codec saferr.Codec[Record[T, U]]) *Writer[T, U] {
+	if codec == nil {
+		codec = saferr.JSONCodec[Record[T, U]]{}
+	}
+	wr := &Writer[T, U]{w: bufio.NewWriter(w), codec: codec}
+	if c, ok := w.(io.Closer); ok {
+		wr.c = c
+	}
+	return wr
+}
+
+// Create creates the file at path, returning a Writer of Records to it
+func Create[T, U any](path string, codec saferr.Codec[Record[T, U]]) (*Writer[T, U], error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriter(f, codec), nil
+}
+
+// Write adds the Record to the recording.  Each Record is framed by its length, so that
+// any Codec can be used
+func (w *Writer[T, U]) Write(r *Record[T, U]) error {
+	data, err := w.codec.Marshal(r)
+	if err != nil {
+		return w.fail(err)
+	}
+
+	w.lck.Lock()
+	defer w.lck.Unlock()
+
+	if w.closed {
+		return ErrWriterClosed
+	}
+
+	var size [4]byte
+	binary.LittleEndian.PutUint32(size[:], uint32(len(data)))
+	if _, err := w.w.Write(size[:]); err != nil {
+		return w.failLocked(err)
+	}
+	if _, err := w.w.Write(data); err != nil {
+		return w.failLocked(err)
+	}
+	return nil
+}
+
+func (w *Writer[T, U]) fail(err error) error {
+	w.lck.Lock()
+	defer w.lck.Unlock()
+	return w.failLocked(err)
+}
+
+func (w *Writer[T, U]) failLocked(err error) error {
+	if w.err == nil {
+		w.err = err
+	}
+	return err
+}
+
+// Err returns the first error encountered writing a Record
+func (w *Writer[T, U]) Err() error {
+	w.lck.Lock()
+	defer w.lck.Unlock()
+	return w.err
+}
+
+// Flush writes any buffered Records
+func (w *Writer[T, U]) Flush() error {
+	w.lck.Lock()
+	defer w.lck.Unlock()
+	return w.w.Flush()
+}
+
+// Close flushes any buffered Records, and closes the underlying io.Writer if it is an io.Closer
+func (w *Writer[T, U]) Close() error {
+	w.lck.Lock()
+	defer w.lck.Unlock()
+
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	err := w.w.Flush()
+	if w.c != nil {
+		err = errors.Join(err, w.c.Close())
+	}
+	return err
+}
+
+// Reader reads the Records of a recording written by a Writer
+type Reader[T, U any] struct {
+	r     *bufio.Reader
+	c     io.Closer
+	codec saferr.Codec[Record[T, U]]
+}
+
+// NewReader returns a Reader of Records from r, decoded by codec.  If codec is nil, saferr.JSONCodec is used
+func NewReader[T, U any](r io.Reader, codec saferr.Codec[Record[T, U]]) *Reader[T, U] {
+	if codec == nil {
+		codec = saferr.JSONCodec[Record[T, U]]{}
+	}
+	rd := &Reader[T, U]{r: bufio.NewReader(r), codec: codec}
+	if c, ok := r.(io.Closer); ok {
+		rd.c = c
+	}
+	return rd
+}
+
+// Open opens the recording at path, returning a Reader of its Records
+func Open[T, U any](path string, codec saferr.Codec[Record[T, U]]) (*Reader[T, U], error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewReader(f, codec), nil
+}
+
+// Read returns the next Record, or io.EOF when there are no more Records
+func (r *Reader[T, U]) Read() (*Record[T, U], error) {
+	var size [4]byte
+	if _, err := io.ReadFull(r.r, size[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, fmt.Errorf("traffic: truncated record: %w", err)
+		}
+		return nil, err
+	}
+
+	data := make([]byte, binary.LittleEndian.Uint32(size[:]))
+	if _, err := io.ReadFull(r.r, data); err != nil {
+		return nil, fmt.Errorf("traffic: truncated record: %w", err)
+	}
+
+	return r.codec.Unmarshal(data)
+}
+
+// Close closes the underlying io.Reader if it is an io.Closer
+func (r *Reader[T, U]) Close() error {
+	if r.c != nil {
+		return r.c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+// Package traffic records the requests and responses flowing through a saferr Responder, and replays
+// recordings into a handler, reporting where its responses differ from those recorded.
+//
+// This allows production issues to be reproduced, and regression checks to be run after a handler changes.
+package traffic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sync/atomic"
+	"time"
+
+	"github.com/gford1000-go/saferr"
+	"github.com/gford1000-go/saferr/types"
+)
+
+type tracedReq[T any] struct {
+	id   uint64
+	sent time.Time
+	t    *T
+}
+
+type requestor[T, U any] struct {
+	requestor types.Requestor[tracedReq[T], U]
+	clock     saferr.Clock
+	nextId    atomic.Uint64
+}
+
+func (r *requestor[T, U]) Send(ctx context.Context, t *T) (*U, error) {
+	return r.requestor.Send(ctx, &tracedReq[T]{id: r.nextId.Add(1), sent: r.clock.Now(), t: t})
+}
+
+// Go behaves as saferr.Go, also writing a Record of each request handled to w.  Errors writing
+// Records do not affect the requests, and are available from w.Err
+func Go[T, U any](ctx context.Context, handler func(context.Context, *T) (*U, error), w *Writer[T, U], opts ...func(*saferr.Options)) types.Requestor[T, U] {
+	var o saferr.Options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	clock := o.Clock
+	if clock == nil {
+		clock = saferr.RealClock()
+	}
+
+	recorded := func(ctx context.Context, r *tracedReq[T]) (u *U, err error) {
+		start := clock.Now()
+
+		returned := false
+		defer func() {
+			var rc any
+			if !returned {
+				rc = recover()
+				err = fmt.Errorf("%w: %v", saferr.ErrUncaughtHandlerPanic, rc)
+			}
+			w.Write(&Record[T, U]{
+				ID:          r.id,
+				Time:        r.sent,
+				Request:     r.t,
+				Response:    u,
+				Err:         types.AsError(err),
+				QueueWait:   start.Sub(r.sent),
+				HandlerTime: clock.Now().Sub(start),
+			})
+			if !returned {
+				// Leave the Responder to report the panic as usual
+				panic(rc)
+			}
+		}()
+
+		u, err = handler(ctx, r.t)
+		returned = true
+		return u, err
+	}
+
+	return &requestor[T, U]{
+		requestor: saferr.Go(ctx, recorded, opts...),
+		clock:     clock,
+	}
+}
+
+// ReplayOptions configure Replay
+type ReplayOptions[U any] struct {
+	// Speed is the multiple of the original speed at which requests are replayed, so that 2 replays
+	// twice as fast.  Zero (the default) replays as fast as possible
+	Speed float64
+	// Equal compares the recorded and replayed responses.  Defaults to reflect.DeepEqual
+	Equal func(recorded, replayed *U) bool
+	// Clock used to wait between requests.  Defaults to the wall clock
+	Clock saferr.Clock
+}
+
+// WithSpeed sets the multiple of the original speed at which requests are replayed
+func WithSpeed[U any](speed float64) func(*ReplayOptions[U]) {
+	return func(o *ReplayOptions[U]) {
+		if speed >= 0 {
+			o.Speed = speed
+		}
+	}
+}
+
+// WithEqual sets the comparison of the recorded and replayed responses
+func WithEqual[U any](f func(recorded, replayed *U) bool) func(*ReplayOptions[U]) {
+	return func(o *ReplayOptions[U]) {
+		if f != nil {
+			o.Equal = f
+		}
+	}
+}
+
+// WithReplayClock sets the Clock used to wait between requests
+func WithReplayClock[U any](c saferr.Clock) func(*ReplayOptions[U]) {
+	return func(o *ReplayOptions[U]) {
+		if c != nil {
+			o.Clock = c
+		}
+	}
+}
+
+// Diff describes a request whose replayed result differs from that recorded
+type Diff[T, U any] struct {
+	// Record of the original request
+	Record *Record[T, U]
+	// Response returned when replayed
+	Response *U
+	// Err returned when replayed
+	Err error
+}
+
+// String describes the difference
+func (d *Diff[T, U]) String() string {
+	return fmt.Sprintf("request %d: recorded %v, %v; replayed %v, %v",
+		d.Record.ID, deref(d.Record.Response), d.Record.Err, deref(d.Response), d.Err)
+}
+
+func deref[U any](u *U) any {
+	if u == nil {
+		return nil
+	}
+	return *u
+}
+
+// Report summarises a Replay
+type Report[T, U any] struct {
+	// Total number of requests replayed
+	Total int
+	// Diffs lists the requests whose replayed results differ from those recorded
+	Diffs []*Diff[T, U]
+}
+
+// Replay sends each Record read from r to handler, running in its own saferr.Go Responder, in the
+// sequence recorded and with the original intervals between requests adjusted by the Speed.
+// The results are compared with those recorded, with any differences listed in the Report
+func Replay[T, U any](ctx context.Context, r *Reader[T, U], handler func(context.Context, *T) (*U, error), opts ...func(*ReplayOptions[U])) (*Report[T, U], error) {
+	o := ReplayOptions[U]{
+		Equal: func(a, b *U) bool { return reflect.DeepEqual(a, b) },
+		Clock: saferr.RealClock(),
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	requestor := saferr.Go(ctx, handler)
+
+	report := &Report[T, U]{}
+	var first time.Time
+	start := o.Clock.Now()
+
+	for {
+		rec, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return report, nil
+		}
+		if err != nil {
+			return report, err
+		}
+
+		if o.Speed > 0 {
+			if first.IsZero() {
+				first = rec.Time
+			}
+			due := start.Add(time.Duration(float64(rec.Time.Sub(first)) / o.Speed))
+			if wait := due.Sub(o.Clock.Now()); wait > 0 {
+				o.Clock.Sleep(wait)
+			}
+		}
+
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		u, err := requestor.Send(ctx, rec.Request)
+		report.Total++
+
+		if !o.Equal(rec.Response, u) || !sameError(rec.Err, err) {
+			report.Diffs = append(report.Diffs, &Diff[T, U]{Record: rec, Response: u, Err: err})
+		}
+	}
+}
+
+// sameError returns true if the replayed error has the same Code and text as the recorded error
+func sameError(recorded *types.Error, replayed error) bool {
+	if recorded == nil || replayed == nil {
+		return recorded == nil && replayed == nil
+	}
+	e := types.AsError(replayed)
+	return e.Code == recorded.Code && e.Error() == recorded.Error()
+}
@@ -0,0 +1,166 @@
+package traffic
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gford1000-go/saferr"
+)
+
+func ExampleReplay() {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var recording bytes.Buffer
+	w := NewWriter[string, string](&recording, nil)
+
+	upper := func(ctx context.Context, s *string) (*string, error) {
+		if *s == "" {
+			return nil, errors.New("empty")
+		}
+		result := strings.ToUpper(*s)
+		return &result, nil
+	}
+
+	requestor := Go(ctx, upper, w)
+	for _, s := range []string{"a", "café", ""} {
+		requestor.Send(ctx, &s)
+	}
+	w.Close()
+
+	// A changed handler, that only converts ASCII letters
+	ascii := func(ctx context.Context, s *string) (*string, error) {
+		if *s == "" {
+			return nil, errors.New("empty")
+		}
+		result := strings.Map(func(r rune) rune {
+			if r >= 'a' && r <= 'z' {
+				return r - 'a' + 'A'
+			}
+			return r
+		}, *s)
+		return &result, nil
+	}
+
+	report, err := Replay(ctx, NewReader[string, string](&recording, nil), ascii)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Println(report.Total, "replayed")
+	for _, d := range report.Diffs {
+		fmt.Println(d)
+	}
+
+	// Output:
+	// 3 replayed
+	// request 2: recorded CAFÉ, <nil>; replayed CAFé, <nil>
+}
+
+func TestGo_Record(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	path := filepath.Join(t.TempDir(), "recording")
+	w, err := Create[int, int](path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clock := saferr.NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	slow := func(ctx context.Context, i *int) (*int, error) {
+		clock.Advance(time.Duration(*i) * time.Second)
+		if *i == 3 {
+			panic("three")
+		}
+		result := *i * 10
+		return &result, nil
+	}
+
+	requestor := Go(ctx, slow, w, saferr.WithClock(clock))
+	for i := 1; i <= 3; i++ {
+		requestor.Send(ctx, &i)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := Open[int, int](path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	for i := 1; i <= 3; i++ {
+		rec, err := r.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rec.ID != uint64(i) || *rec.Request != i || rec.HandlerTime != time.Duration(i)*time.Second {
+			t.Fatalf("unexpected record: %+v", rec)
+		}
+		if i < 3 && (rec.Err != nil || *rec.Response != i*10) {
+			t.Fatalf("unexpected response: %+v", rec)
+		}
+		if i == 3 && !errors.Is(rec.Err, saferr.ErrUncaughtHandlerPanic) {
+			t.Fatalf("expected the panic to be recorded, got %v", rec.Err)
+		}
+	}
+	if _, err := r.Read(); err != io.EOF {
+		t.Fatalf("expected io.EOF, got %v", err)
+	}
+}
+
+func TestReplay_Speed(t *testing.T) {
+
+	var recording bytes.Buffer
+	w := NewWriter[int, int](&recording, nil)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 3 {
+		v := i
+		w.Write(&Record[int, int]{ID: uint64(i + 1), Time: start.Add(time.Duration(i) * time.Minute), Request: &v, Response: &v})
+	}
+	w.Close()
+
+	clock := saferr.NewFakeClock(start)
+	echo := func(ctx context.Context, i *int) (*int, error) { return i, nil }
+
+	done := make(chan *Report[int, int], 1)
+	go func() {
+		report, err := Replay(context.Background(), NewReader[int, int](&recording, nil), echo,
+			WithSpeed[int](2), WithReplayClock[int](clock))
+		if err != nil {
+			t.Error(err)
+		}
+		done <- report
+	}()
+
+	// At twice the speed, the requests a minute apart are replayed 30 seconds apart
+	for range 2 {
+		clock.BlockUntil(1)
+		clock.Advance(30 * time.Second)
+	}
+
+	report := <-done
+	if report.Total != 3 || len(report.Diffs) != 0 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if elapsed := clock.Now().Sub(start); elapsed != time.Minute {
+		t.Fatalf("expected replay to take 1m, took %v", elapsed)
+	}
+}
@@ -0,0 +1,280 @@
+package types
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Code classifies an Error, so that callers can decide how to react without
+// having to inspect error messages
+type Code int
+
+const (
+	// CodeUnknown is used when no other classification is available
+	CodeUnknown Code = iota
+	// CodeInvalidArgument indicates the request was malformed and should not be retried as is
+	CodeInvalidArgument
+	// CodeNotFound indicates the requested entity (or handler) does not exist
+	CodeNotFound
+	// CodeAlreadyExists indicates an attempt to create an entity that already exists
+	CodeAlreadyExists
+	// CodeFailedPrecondition indicates the system is not in a state to process the request
+	CodeFailedPrecondition
+	// CodePermissionDenied indicates the caller is not allowed to make the request
+	CodePermissionDenied
+	// CodeResourceExhausted indicates a limit (such as a buffer or quota) has been reached
+	CodeResourceExhausted
+	// CodeDeadlineExceeded indicates the request did not complete in the time allowed
+	CodeDeadlineExceeded
+	// CodeCanceled indicates the request was cancelled, typically by its context
+	CodeCanceled
+	// CodeUnavailable indicates the service is currently unable to handle the request
+	CodeUnavailable
+	// CodeUnimplemented indicates the operation is not supported
+	CodeUnimplemented
+	// CodeInternal indicates an unexpected failure inside the service
+	CodeInternal
+)
+
+var codeNames = map[Code]string{
+	CodeUnknown:            "unknown",
+	CodeInvalidArgument:    "invalid_argument",
+	CodeNotFound:           "not_found",
+	CodeAlreadyExists:      "already_exists",
+	CodeFailedPrecondition: "failed_precondition",
+	CodePermissionDenied:   "permission_denied",
+	CodeResourceExhausted:  "resource_exhausted",
+	CodeDeadlineExceeded:   "deadline_exceeded",
+	CodeCanceled:           "canceled",
+	CodeUnavailable:        "unavailable",
+	CodeUnimplemented:      "unimplemented",
+	CodeInternal:           "internal",
+}
+
+// String returns the stable name of the Code
+func (c Code) String() string {
+	if s, ok := codeNames[c]; ok {
+		return s
+	}
+	return fmt.Sprintf("code(%d)", int(c))
+}
+
+// MarshalText ensures the Code is serialised by name rather than by value,
+// so that codes remain stable across versions
+func (c Code) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// UnmarshalText restores a Code from its name
+func (c *Code) UnmarshalText(b []byte) error {
+	s := string(b)
+	for k, v := range codeNames {
+		if v == s {
+			*c = k
+			return nil
+		}
+	}
+	var n int
+	if _, err := fmt.Sscanf(s, "code(%d)", &n); err == nil {
+		*c = Code(n)
+		return nil
+	}
+	return fmt.Errorf("unknown error code: %q", s)
+}
+
+// Error is a structured error, carrying a Code, whether the failed request may be retried,
+// optional details and an optional underlying cause
+type Error struct {
+	// Code classifies the error
+	Code Code
+	// Message is a human readable description of the error
+	Message string
+	// Retryable indicates whether the same request may succeed if attempted again
+	Retryable bool
+	// Details contains optional additional information about the error
+	Details map[string]any
+	// Cause is the underlying error, if any
+	Cause error
+}
+
+// ErrHandlerPanic is returned if a panic occurs when handling a request.  It is shared by saferr (as
+// ErrUncaughtHandlerPanic) and mux (as ErrHandlerPanic), which cannot import each other
+var ErrHandlerPanic = &Error{Code: CodeInternal, Message: "recovered receiver panic during handling"}
+
+// NewError returns an Error with the specified code and message
+func NewError(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Errorf returns an Error with the specified code and a formatted message.
+// If the format contains a %w verb, the wrapped error becomes the Cause
+func Errorf(code Code, format string, args ...any) *Error {
+	err := fmt.Errorf(format, args...)
+	return &Error{Code: code, Message: err.Error(), Cause: errors.Unwrap(err)}
+}
+
+// Wrap returns an Error with the specified code and message, wrapping cause
+func Wrap(code Code, cause error, message string) *Error {
+	return &Error{Code: code, Message: message, Cause: cause}
+}
+
+// Error returns the message, followed by the cause if the message does not already describe it
+func (e *Error) Error() string {
+	if e.Cause == nil {
+		return e.Message
+	}
+	cause := e.Cause.Error()
+	switch {
+	case e.Message == "":
+		return cause
+	case strings.Contains(e.Message, cause):
+		return e.Message
+	}
+	return e.Message + ": " + cause
+}
+
+// Unwrap returns the Cause, supporting errors.Is and errors.As
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+// Is reports a match if the target is an *Error with the same Code and Message.
+// This allows errors that have been serialised and restored to still match the
+// sentinel errors they were created from
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok {
+		return false
+	}
+	return t.Code == e.Code && t.Message == e.Message
+}
+
+// WithDetail returns a copy of the Error with the additional detail added.
+// A copy is returned so that sentinel errors are never modified
+func (e *Error) WithDetail(key string, value any) *Error {
+	c := *e
+	c.Details = make(map[string]any, len(e.Details)+1)
+	for k, v := range e.Details {
+		c.Details[k] = v
+	}
+	c.Details[key] = value
+	return &c
+}
+
+// WithCause returns a copy of the Error with the specified Cause
+func (e *Error) WithCause(cause error) *Error {
+	c := *e
+	c.Cause = cause
+	return &c
+}
+
+type errorJSON struct {
+	Code      Code           `json:"code"`
+	Message   string         `json:"message"`
+	Retryable bool           `json:"retryable,omitempty"`
+	Details   map[string]any `json:"details,omitempty"`
+	Cause     *errorJSON     `json:"cause,omitempty"`
+}
+
+func toErrorJSON(err error) *errorJSON {
+	if err == nil {
+		return nil
+	}
+	e := AsError(err)
+	return &errorJSON{
+		Code:      e.Code,
+		Message:   e.Message,
+		Retryable: e.Retryable,
+		Details:   e.Details,
+		Cause:     toErrorJSON(e.Cause),
+	}
+}
+
+func (j *errorJSON) toError() *Error {
+	e := &Error{
+		Code:      j.Code,
+		Message:   j.Message,
+		Retryable: j.Retryable,
+		Details:   j.Details,
+	}
+	if j.Cause != nil {
+		e.Cause = j.Cause.toError()
+	}
+	return e
+}
+
+// MarshalJSON serialises the Error, including the chain of causes, retaining their codes
+func (e *Error) MarshalJSON() ([]byte, error) {
+	return json.Marshal(toErrorJSON(e))
+}
+
+// UnmarshalJSON restores an Error, including the chain of causes
+func (e *Error) UnmarshalJSON(b []byte) error {
+	var j errorJSON
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	*e = *j.toError()
+	return nil
+}
+
+// GobEncode serialises the Error as MarshalJSON does, since gob cannot encode the Cause or the Details.
+// As with JSON, the Details are restored as JSON values (for example numbers as float64)
+func (e *Error) GobEncode() ([]byte, error) {
+	return e.MarshalJSON()
+}
+
+// GobDecode restores an Error serialised by GobEncode
+func (e *Error) GobDecode(b []byte) error {
+	return e.UnmarshalJSON(b)
+}
+
+// AsError returns err as an *Error, so that it can be inspected or serialised.
+// If err is (or wraps) an *Error then that *Error is used, with any outer
+// wrapping message preserved; context errors are mapped to their corresponding codes;
+// all other errors are returned with CodeUnknown.  Returns nil if err is nil.
+func AsError(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		if e == err {
+			return e
+		}
+		// err wraps an *Error, so retain the outer message but the inner classification
+		return &Error{
+			Code:      e.Code,
+			Message:   err.Error(),
+			Retryable: e.Retryable,
+			Details:   e.Details,
+			Cause:     e,
+		}
+	}
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return &Error{Code: CodeDeadlineExceeded, Message: err.Error(), Retryable: true}
+	case errors.Is(err, context.Canceled):
+		return &Error{Code: CodeCanceled, Message: err.Error()}
+	}
+	return &Error{Code: CodeUnknown, Message: err.Error()}
+}
+
+// CodeOf returns the Code of err, or CodeUnknown if none can be determined
+func CodeOf(err error) Code {
+	if err == nil {
+		return CodeUnknown
+	}
+	return AsError(err).Code
+}
+
+// IsRetryable returns true if err is (or wraps) an *Error that is marked as Retryable
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	return AsError(err).Retryable
+}
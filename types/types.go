@@ -10,9 +10,21 @@ type Requestor[T any, U any] interface {
 	Send(ctx context.Context, t *T) (*U, error)
 }
 
+// PriorityRequestor is a Requestor that can also send requests using one of several priority lanes,
+// where lane 0 has the highest priority
+type PriorityRequestor[T any, U any] interface {
+	Requestor[T, U]
+	// SendWithPriority sends a single request using the specified priority lane
+	SendWithPriority(ctx context.Context, priority int, t *T) (*U, error)
+}
+
 // Handler processes a request of type *T into the result *U or an error
 type Handler[T any, U any] func(ctx context.Context, t *T) (*U, error)
 
+// BatchHandler processes several requests together, returning a response and an error for each request,
+// in the same sequence.  The errors may be nil if all requests succeeded
+type BatchHandler[T any, U any] func(ctx context.Context, ts []*T) ([]*U, []error)
+
 // Responder handles requests from the associated Requestor
 type Responder[T any, U any] interface {
 	// ListenAndHandle invokes the requestHandler to generate the response
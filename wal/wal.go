@@ -0,0 +1,508 @@
+// Package wal provides a write-ahead log of requests, so that requests that have been accepted
+// but not yet handled are not lost if the process stops.
+//
+// Each request is appended to the Log, and marked complete once it has been handled.  When the Log
+// is opened, requests that were appended but never completed are available from Pending, so that
+// they can be replayed.  The Log is split into segment files, which are removed once all of their
+// requests are complete.
+package wal
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gford1000-go/saferr/types"
+)
+
+// ErrCorrupt is returned by Open if a segment contains a corrupt record and the RecoveryPolicy is RecoverFail
+var ErrCorrupt = &types.Error{Code: types.CodeFailedPrecondition, Message: "wal segment is corrupt"}
+
+// ErrClosed is returned if the Log is used after Close
+var ErrClosed = &types.Error{Code: types.CodeFailedPrecondition, Message: "wal is closed"}
+
+// ErrRecordTooLarge is returned if a record exceeds the maximum size of a record
+var ErrRecordTooLarge = &types.Error{Code: types.CodeInvalidArgument, Message: "wal record too large"}
+
+// SyncPolicy determines when appended records are flushed to stable storage
+type SyncPolicy int
+
+const (
+	// SyncAlways flushes each appended record before Append returns
+	SyncAlways SyncPolicy = iota
+	// SyncInterval flushes periodically, so that records appended since the last flush may be lost
+	// if the operating system stops, although not if only the process stops
+	SyncInterval
+	// SyncNever leaves flushing to the operating system
+	SyncNever
+)
+
+// RecoveryPolicy determines how Open handles a corrupt record, such as one partially written when the process stopped
+type RecoveryPolicy int
+
+const (
+	// RecoverTruncate discards the corrupt record and the remainder of its segment
+	RecoverTruncate RecoveryPolicy = iota
+	// RecoverFail returns ErrCorrupt from Open
+	RecoverFail
+)
+
+// Options configure the Log
+type Options struct {
+	// SegmentSize is the size in bytes after which a new segment file is started
+	SegmentSize int64
+	// Sync determines when appended records are flushed to stable storage.  Records are always written
+	// to the operating system before Append or Complete return, but completions are never flushed to
+	// stable storage explicitly, so a request may occasionally be replayed after it was handled
+	Sync SyncPolicy
+	// SyncInterval is the period between flushes, when Sync is SyncInterval
+	SyncInterval time.Duration
+	// Recovery determines how Open handles a corrupt record
+	Recovery RecoveryPolicy
+}
+
+var defaults = Options{
+	SegmentSize:  64 << 20,
+	Sync:         SyncAlways,
+	SyncInterval: 100 * time.Millisecond,
+	Recovery:     RecoverTruncate,
+}
+
+// WithSegmentSize sets the size in bytes after which a new segment file is started.  Default: 64MB
+func WithSegmentSize(size int64) func(*Options) {
+	return func(o *Options) {
+		if size > 0 {
+			o.SegmentSize = size
+		}
+	}
+}
+
+// WithSync sets the SyncPolicy, with the interval used by SyncInterval.  Default: SyncAlways
+func WithSync(policy SyncPolicy, interval time.Duration) func(*Options) {
+	return func(o *Options) {
+		o.Sync = policy
+		if interval > 0 {
+			o.SyncInterval = interval
+		}
+	}
+}
+
+// WithRecovery sets the RecoveryPolicy.  Default: RecoverTruncate
+func WithRecovery(policy RecoveryPolicy) func(*Options) {
+	return func(o *Options) {
+		o.Recovery = policy
+	}
+}
+
+// Record is a request appended to the Log
+type Record struct {
+	ID   uint64
+	Data []byte
+}
+
+const (
+	recordAppend   byte = 1
+	recordComplete byte = 2
+
+	headerSize    = 8 // length and checksum
+	maxRecordSize = 1 << 30
+	segmentSuffix = ".wal"
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+type segment struct {
+	seq     uint64
+	path    string
+	pending int // appended records not yet complete
+}
+
+// Log is a write-ahead log, safe for concurrent use
+type Log struct {
+	dir     string
+	opts    Options
+	lck     sync.Mutex
+	segs    []*segment
+	owner   map[uint64]*segment
+	active  *os.File
+	w       *bufio.Writer
+	size    int64
+	nextId  uint64
+	pending []Record
+	closed  bool
+	dirty   bool
+	stop    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+}
+
+// Open opens the Log in dir, creating dir if necessary, and recovers the requests that were
+// appended but not completed, which are then available from Pending
+func Open(dir string, opts ...func(*Options)) (*Log, error) {
+	o := defaults
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	l := &Log{
+		dir:    dir,
+		opts:   o,
+		owner:  map[uint64]*segment{},
+		nextId: 1,
+	}
+
+	if err := l.recover(); err != nil {
+		return nil, err
+	}
+
+	// New records always start a new segment, so that recovered segments are never appended to
+	var seq uint64 = 1
+	if n := len(l.segs); n > 0 {
+		seq = l.segs[n-1].seq + 1
+	}
+	if err := l.rotate(seq); err != nil {
+		return nil, err
+	}
+	if err := l.removeCompleted(); err != nil {
+		return nil, err
+	}
+
+	if o.Sync == SyncInterval {
+		l.stop = make(chan struct{})
+		l.stopped = make(chan struct{})
+		go l.syncLoop()
+	}
+
+	return l, nil
+}
+
+// recover reads each segment in sequence, collecting the records that were not completed
+func (l *Log) recover() error {
+	entries, err := os.ReadDir(l.dir)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, segmentSuffix) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, segmentSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		l.segs = append(l.segs, &segment{seq: seq, path: filepath.Join(l.dir, name)})
+	}
+	sort.Slice(l.segs, func(i, j int) bool { return l.segs[i].seq < l.segs[j].seq })
+
+	data := map[uint64][]byte{}
+
+	for _, s := range l.segs {
+		if err := l.readSegment(s, data); err != nil {
+			return err
+		}
+	}
+
+	for id, d := range data {
+		l.pending = append(l.pending, Record{ID: id, Data: d})
+	}
+	sort.Slice(l.pending, func(i, j int) bool { return l.pending[i].ID < l.pending[j].ID })
+
+	return nil
+}
+
+func (l *Log) readSegment(s *segment, data map[uint64][]byte) error {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	var offset int64
+
+	for {
+		kind, id, payload, n, err := readRecord(r)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			if l.opts.Recovery == RecoverFail {
+				return fmt.Errorf("%w: %s at offset %d: %v", ErrCorrupt, s.path, offset, err)
+			}
+			f.Close()
+			return os.Truncate(s.path, offset)
+		}
+		offset += n
+
+		if id >= l.nextId {
+			l.nextId = id + 1
+		}
+
+		switch kind {
+		case recordAppend:
+			data[id] = payload
+			l.owner[id] = s
+			s.pending++
+		case recordComplete:
+			if owner, ok := l.owner[id]; ok {
+				delete(data, id)
+				delete(l.owner, id)
+				owner.pending--
+			}
+		}
+	}
+}
+
+// readRecord returns the next record, with the number of bytes read.  io.EOF is only returned
+// if there are no further records
+func readRecord(r io.Reader) (kind byte, id uint64, payload []byte, n int64, err error) {
+	var header [headerSize]byte
+	if _, err = io.ReadFull(r, header[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = errors.New("truncated header")
+		}
+		return
+	}
+
+	size := binary.LittleEndian.Uint32(header[0:4])
+	crc := binary.LittleEndian.Uint32(header[4:8])
+	if size < 9 || size > maxRecordSize {
+		err = fmt.Errorf("invalid record size %d", size)
+		return
+	}
+
+	body := make([]byte, size)
+	if _, err = io.ReadFull(r, body); err != nil {
+		err = errors.New("truncated record")
+		return
+	}
+	if crc32.Checksum(body, crcTable) != crc {
+		err = errors.New("checksum mismatch")
+		return
+	}
+
+	return body[0], binary.LittleEndian.Uint64(body[1:9]), body[9:], int64(headerSize + size), nil
+}
+
+// Pending returns the records that were recovered by Open and have not since been completed, in the order appended
+func (l *Log) Pending() []Record {
+	l.lck.Lock()
+	defer l.lck.Unlock()
+
+	var result []Record
+	for _, r := range l.pending {
+		if _, ok := l.owner[r.ID]; ok {
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+// Append adds a record containing data to the Log, returning its ID.  With SyncAlways, the record
+// is flushed to stable storage before Append returns
+func (l *Log) Append(data []byte) (uint64, error) {
+	if len(data) > maxRecordSize-9 {
+		return 0, ErrRecordTooLarge
+	}
+
+	l.lck.Lock()
+	defer l.lck.Unlock()
+
+	if l.closed {
+		return 0, ErrClosed
+	}
+
+	id := l.nextId
+	if err := l.write(recordAppend, id, data); err != nil {
+		return 0, err
+	}
+	l.nextId++
+
+	s := l.segs[len(l.segs)-1]
+	l.owner[id] = s
+	s.pending++
+
+	if l.opts.Sync == SyncAlways {
+		if err := l.sync(); err != nil {
+			return 0, err
+		}
+	} else if err := l.w.Flush(); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// Complete marks the record with the ID as complete, so that it is not recovered when the Log is next opened.
+// Completing an unknown or already completed ID has no effect
+func (l *Log) Complete(id uint64) error {
+	l.lck.Lock()
+	defer l.lck.Unlock()
+
+	if l.closed {
+		return ErrClosed
+	}
+
+	owner, ok := l.owner[id]
+	if !ok {
+		return nil
+	}
+
+	if err := l.write(recordComplete, id, nil); err != nil {
+		return err
+	}
+
+	delete(l.owner, id)
+	owner.pending--
+
+	// Write to the file, but avoid the cost of a sync
+	if err := l.w.Flush(); err != nil {
+		return err
+	}
+
+	return l.removeCompleted()
+}
+
+// write adds a record to the active segment, rotating first if the segment is full
+func (l *Log) write(kind byte, id uint64, data []byte) error {
+	size := headerSize + 9 + int64(len(data))
+	if l.size > 0 && l.size+size > l.opts.SegmentSize {
+		if err := l.rotate(l.segs[len(l.segs)-1].seq + 1); err != nil {
+			return err
+		}
+	}
+
+	body := make([]byte, 9+len(data))
+	body[0] = kind
+	binary.LittleEndian.PutUint64(body[1:9], id)
+	copy(body[9:], data)
+
+	var header [headerSize]byte
+	binary.LittleEndian.PutUint32(header[0:4], uint32(len(body)))
+	binary.LittleEndian.PutUint32(header[4:8], crc32.Checksum(body, crcTable))
+
+	if _, err := l.w.Write(header[:]); err != nil {
+		return err
+	}
+	if _, err := l.w.Write(body); err != nil {
+		return err
+	}
+
+	l.size += size
+	l.dirty = true
+	return nil
+}
+
+// rotate closes the active segment and starts a new segment with the sequence number seq
+func (l *Log) rotate(seq uint64) error {
+	if l.active != nil {
+		if err := l.sync(); err != nil {
+			return err
+		}
+		if err := l.active.Close(); err != nil {
+			return err
+		}
+	}
+
+	path := filepath.Join(l.dir, fmt.Sprintf("%020d%s", seq, segmentSuffix))
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+
+	l.active = f
+	l.w = bufio.NewWriter(f)
+	l.size = 0
+	l.segs = append(l.segs, &segment{seq: seq, path: path})
+	return nil
+}
+
+// removeCompleted deletes the oldest segments, other than the active (last) segment, whose records are all
+// complete.  Segments are only removed in sequence, since a later segment may contain completions for an earlier one
+func (l *Log) removeCompleted() error {
+	for len(l.segs) > 1 && l.segs[0].pending == 0 {
+		if err := os.Remove(l.segs[0].path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		l.segs = l.segs[1:]
+	}
+	return nil
+}
+
+func (l *Log) sync() error {
+	if !l.dirty {
+		return nil
+	}
+	if err := l.w.Flush(); err != nil {
+		return err
+	}
+	if err := l.active.Sync(); err != nil {
+		return err
+	}
+	l.dirty = false
+	return nil
+}
+
+// Sync flushes all records to stable storage
+func (l *Log) Sync() error {
+	l.lck.Lock()
+	defer l.lck.Unlock()
+
+	if l.closed {
+		return ErrClosed
+	}
+	return l.sync()
+}
+
+func (l *Log) syncLoop() {
+	defer close(l.stopped)
+
+	ticker := time.NewTicker(l.opts.SyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			l.Sync()
+		}
+	}
+}
+
+// Close flushes all records to stable storage and closes the Log
+func (l *Log) Close() error {
+	if l.stop != nil {
+		l.once.Do(func() {
+			close(l.stop)
+			<-l.stopped
+		})
+	}
+
+	l.lck.Lock()
+	defer l.lck.Unlock()
+
+	if l.closed {
+		return nil
+	}
+	l.closed = true
+
+	err := l.sync()
+	return errors.Join(err, l.active.Close())
+}
@@ -0,0 +1,203 @@
+package wal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func pendingData(l *Log) string {
+	var s []string
+	for _, r := range l.Pending() {
+		s = append(s, fmt.Sprintf("%d:%s", r.ID, r.Data))
+	}
+	return fmt.Sprint(s)
+}
+
+func TestLog_Recover(t *testing.T) {
+
+	dir := t.TempDir()
+
+	l, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, s := range []string{"a", "b", "c"} {
+		if _, err := l.Append([]byte(s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.Complete(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := l.Append([]byte("d")); !errors.Is(err, ErrClosed) {
+		t.Fatalf("expected ErrClosed, got %v", err)
+	}
+
+	l, err = Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	if got := pendingData(l); got != "[1:a 3:c]" {
+		t.Fatalf("unexpected pending records: %s", got)
+	}
+
+	// IDs continue from those recovered
+	id, err := l.Append([]byte("d"))
+	if err != nil || id != 4 {
+		t.Fatalf("expected id 4, got %d, %v", id, err)
+	}
+
+	l.Complete(1)
+	if got := pendingData(l); got != "[3:c]" {
+		t.Fatalf("unexpected pending records after Complete: %s", got)
+	}
+}
+
+func segments(t *testing.T, dir string) []string {
+	t.Helper()
+	names, err := filepath.Glob(filepath.Join(dir, "*"+segmentSuffix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return names
+}
+
+func TestLog_Rotation(t *testing.T) {
+
+	dir := t.TempDir()
+
+	l, err := Open(dir, WithSegmentSize(100), WithSync(SyncNever, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []uint64
+	for i := range 20 {
+		id, err := l.Append(fmt.Appendf(nil, "record-%02d", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+
+	if n := len(segments(t, dir)); n < 5 {
+		t.Fatalf("expected the log to be split into several segments, got %d", n)
+	}
+
+	for _, id := range ids {
+		if err := l.Complete(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n := len(segments(t, dir)); n != 1 {
+		t.Fatalf("expected completed segments to be removed, got %d", n)
+	}
+	l.Close()
+
+	l, err = Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	if got := len(l.Pending()); got != 0 {
+		t.Fatalf("expected no pending records, got %d", got)
+	}
+}
+
+func TestLog_Corruption(t *testing.T) {
+
+	dir := t.TempDir()
+
+	l, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Append([]byte("first"))
+	l.Append([]byte("second"))
+	l.Close()
+
+	// Simulate a partial write of the last record
+	path := segments(t, dir)[0]
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(path, info.Size()-3); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Open(dir, WithRecovery(RecoverFail)); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("expected ErrCorrupt, got %v", err)
+	}
+
+	l, err = Open(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer l.Close()
+
+	if got := pendingData(l); got != "[1:first]" {
+		t.Fatalf("unexpected pending records: %s", got)
+	}
+
+	// Flip a byte within the remaining record, so that its checksum fails
+	l.Close()
+	data, _ := os.ReadFile(path)
+	data[len(data)-1] ^= 0xff
+	os.WriteFile(path, data, 0o644)
+
+	l, err = Open(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer l.Close()
+
+	if got := len(l.Pending()); got != 0 {
+		t.Fatalf("expected the corrupt record to be discarded, got %d", got)
+	}
+}
+
+func TestLog_SyncInterval(t *testing.T) {
+
+	dir := t.TempDir()
+
+	l, err := Open(dir, WithSync(SyncInterval, time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := l.Append([]byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatalf("expected Close to be idempotent, got %v", err)
+	}
+
+	l, err = Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	if got := pendingData(l); got != "[1:a]" {
+		t.Fatalf("unexpected pending records: %s", got)
+	}
+}